@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Content block types
+const (
+	ContentTypeText         = "text"
+	ContentTypeImage        = "image"
+	ContentTypeAudio        = "audio"
+	ContentTypeResourceLink = "resource_link"
+	ContentTypeResource     = "resource"
+)
+
+// Text returns a text content block
+func Text(text string) TextContent {
+	return TextContent{
+		Type: ContentTypeText,
+		Text: text,
+	}
+}
+
+// Image returns an image content block with the given (raw) data
+func Image(data []byte, mimeType string) ImageContent {
+	return ImageContent{
+		Type:     ContentTypeImage,
+		Data:     EncodeBase64(data),
+		MimeType: mimeType,
+	}
+}
+
+// Audio returns an audio content block with the given (raw) data
+func Audio(data []byte, mimeType string) AudioContent {
+	return AudioContent{
+		Type:     ContentTypeAudio,
+		Data:     EncodeBase64(data),
+		MimeType: mimeType,
+	}
+}
+
+// AsLink returns a content block with a link to the resource
+func (r Resource) AsLink() ResourceLink {
+	return ResourceLink{
+		Meta:        r.Meta,
+		Annotations: r.Annotations,
+		Type:        ContentTypeResourceLink,
+		Uri:         r.Uri,
+		Name:        r.Name,
+		Title:       r.Title,
+		Description: r.Description,
+		MimeType:    r.MimeType,
+		Size:        r.Size,
+	}
+}
+
+// EmbeddedText returns a content block with an embedded text resource (the MIME type is optional)
+func EmbeddedText(uri, text, mimeType string) EmbeddedResource {
+	return EmbeddedResource{
+		Type: ContentTypeResource,
+		Resource: EmbeddedResourceResource{
+			Uri:      uri,
+			Text:     text,
+			MimeType: optionalString(mimeType),
+		},
+	}
+}
+
+// EmbeddedBlob returns a content block with an embedded binary resource with the given (raw) data
+// (the MIME type is optional)
+func EmbeddedBlob(uri string, data []byte, mimeType string) EmbeddedResource {
+	return EmbeddedResource{
+		Type: ContentTypeResource,
+		Resource: EmbeddedResourceResource{
+			Uri:      uri,
+			Blob:     EncodeBase64(data),
+			MimeType: optionalString(mimeType),
+		},
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// EncodeBase64 encodes binary data for the `data` and `blob` fields
+func EncodeBase64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// DecodeBase64 decodes the `data` and `blob` fields
+func DecodeBase64(data string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// Bytes returns the decoded image data
+func (c ImageContent) Bytes() ([]byte, error) {
+	return DecodeBase64(c.Data)
+}
+
+// Bytes returns the decoded audio data
+func (c AudioContent) Bytes() ([]byte, error) {
+	return DecodeBase64(c.Data)
+}
+
+// Bytes returns the decoded blob
+func (c BlobResourceContents) Bytes() ([]byte, error) {
+	return DecodeBase64(c.Blob)
+}
+
+// UnmarshalContentBlock decodes a content block into a `TextContent`, `ImageContent`, `AudioContent`,
+// `ResourceLink` or `EmbeddedResource` value, according to its `type` field
+func UnmarshalContentBlock(data []byte) (ContentBlock, error) {
+	discriminator := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(data, &discriminator); err != nil {
+		return nil, err
+	}
+	switch discriminator.Type {
+	case ContentTypeText:
+		return unmarshalAs[TextContent](data)
+	case ContentTypeImage:
+		return unmarshalAs[ImageContent](data)
+	case ContentTypeAudio:
+		return unmarshalAs[AudioContent](data)
+	case ContentTypeResourceLink:
+		return unmarshalAs[ResourceLink](data)
+	case ContentTypeResource:
+		return unmarshalAs[EmbeddedResource](data)
+	default:
+		return nil, fmt.Errorf("unknown content block type: '%s'", discriminator.Type)
+	}
+}
+
+func unmarshalAs[T any](data []byte) (ContentBlock, error) {
+	var c T
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DecodeContentBlock converts a content block decoded as a `map[string]any` (eg: in `CallToolResult.Content`)
+// into its concrete type. Blocks which already have a concrete type are returned as-is.
+func DecodeContentBlock(block any) (ContentBlock, error) {
+	switch block.(type) {
+	case TextContent, ImageContent, AudioContent, ResourceLink, EmbeddedResource:
+		return block, nil
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalContentBlock(data)
+}
+
+// DecodeContentBlocks converts the given content blocks into their concrete types (see `DecodeContentBlock`).
+// The type of the elements is usually a union type, such as `ContentBlock` or `CallToolResultContentElem`.
+// An error is returned if a decoded block is not assignable to that type.
+func DecodeContentBlocks[T any](blocks []T) ([]T, error) {
+	result := make([]T, 0, len(blocks))
+	for i, b := range blocks {
+		c, err := DecodeContentBlock(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content block at index %d: %w", i, err)
+		}
+		e, ok := c.(T)
+		if !ok {
+			return nil, fmt.Errorf("invalid content block at index %d: %T is not a %s", i, c, reflect.TypeFor[T]())
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// AsSamplingContent returns the text as the content of a sampling message
+func (c TextContent) AsSamplingContent() SamplingMessageContent {
+	return SamplingMessageContent{Meta: c.Meta, Annotations: c.Annotations, Type: c.Type, Text: c.Text}
+}
+
+// AsSamplingContent returns the image as the content of a sampling message
+func (c ImageContent) AsSamplingContent() SamplingMessageContent {
+	return SamplingMessageContent{Meta: c.Meta, Annotations: c.Annotations, Type: c.Type, Data: c.Data, MimeType: c.MimeType}
+}
+
+// AsSamplingContent returns the audio as the content of a sampling message
+func (c AudioContent) AsSamplingContent() SamplingMessageContent {
+	return SamplingMessageContent{Meta: c.Meta, Annotations: c.Annotations, Type: c.Type, Data: c.Data, MimeType: c.MimeType}
+}
+
+// ContentBlock returns the content as a `TextContent`, `ImageContent` or `AudioContent` value, according to its type
+func (c SamplingMessageContent) ContentBlock() ContentBlock {
+	return mediaContentBlock(c.Meta, c.Annotations, c.Type, c.Text, c.Data, c.MimeType)
+}
+
+// ContentBlock returns the content as a `TextContent`, `ImageContent` or `AudioContent` value, according to its type
+func (c CreateMessageResultContent) ContentBlock() ContentBlock {
+	return mediaContentBlock(c.Meta, c.Annotations, c.Type, c.Text, c.Data, c.MimeType)
+}
+
+func mediaContentBlock(meta map[string]any, annotations *Annotations, contentType, text, data, mimeType string) ContentBlock {
+	switch contentType {
+	case ContentTypeImage:
+		return ImageContent{Meta: meta, Annotations: annotations, Type: contentType, Data: data, MimeType: mimeType}
+	case ContentTypeAudio:
+		return AudioContent{Meta: meta, Annotations: annotations, Type: contentType, Data: data, MimeType: mimeType}
+	default:
+		return TextContent{Meta: meta, Annotations: annotations, Type: contentType, Text: text}
+	}
+}
+
+// The following types are generated as the union of the fields of their variants, so they are encoded
+// as their actual variant, to avoid sending the empty fields of the other variants
+
+// MarshalJSON implements json.Marshaler.
+func (c SamplingMessageContent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ContentBlock())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c CreateMessageResultContent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ContentBlock())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r EmbeddedResourceResource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resourceContents(r.Meta, r.Uri, r.MimeType, r.Text, r.Blob))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r ReadResourceResultContentsElem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resourceContents(r.Meta, r.Uri, r.MimeType, r.Text, r.Blob))
+}
+
+func resourceContents(meta map[string]any, uri string, mimeType *string, text, blob string) any {
+	if blob != "" {
+		return BlobResourceContents{Meta: meta, Uri: uri, MimeType: mimeType, Blob: blob}
+	}
+	return TextResourceContents{Meta: meta, Uri: uri, MimeType: mimeType, Text: text}
+}
@@ -0,0 +1,139 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentBlocks(t *testing.T) {
+
+	t.Run("encode", func(t *testing.T) {
+		// given
+		result := api.CallToolResult{
+			Content: []api.CallToolResultContentElem{
+				api.Text("hello"),
+				api.Image([]byte("png"), "image/png"),
+				api.Audio([]byte("wav"), "audio/wav"),
+				api.NewResource("README", "file:///README.md").AsLink(),
+				api.EmbeddedText("file:///hello.txt", "hello", "text/plain"),
+				api.EmbeddedBlob("file:///hello.bin", []byte("hello"), ""),
+			},
+		}
+
+		// when
+		actual, err := json.Marshal(result)
+
+		// then
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"content": [
+				{"type": "text", "text": "hello"},
+				{"type": "image", "data": "cG5n", "mimeType": "image/png"},
+				{"type": "audio", "data": "d2F2", "mimeType": "audio/wav"},
+				{"type": "resource_link", "uri": "file:///README.md", "name": "README"},
+				{"type": "resource", "resource": {"uri": "file:///hello.txt", "text": "hello", "mimeType": "text/plain"}},
+				{"type": "resource", "resource": {"uri": "file:///hello.bin", "blob": "aGVsbG8="}}
+			]
+		}`, string(actual))
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		// given
+		data := `{
+			"content": [
+				{"type": "text", "text": "hello"},
+				{"type": "image", "data": "cG5n", "mimeType": "image/png"},
+				{"type": "resource", "resource": {"uri": "file:///hello.bin", "blob": "aGVsbG8="}}
+			]
+		}`
+		result := api.CallToolResult{}
+		require.NoError(t, json.Unmarshal([]byte(data), &result))
+
+		// when
+		content, err := api.DecodeContentBlocks(result.Content)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, content, 3)
+		assert.Equal(t, api.Text("hello"), content[0])
+		require.IsType(t, api.ImageContent{}, content[1])
+		image, err := content[1].(api.ImageContent).Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("png"), image)
+		require.IsType(t, api.EmbeddedResource{}, content[2])
+		assert.Equal(t, "aGVsbG8=", content[2].(api.EmbeddedResource).Resource.Blob)
+	})
+
+	t.Run("decode into a concrete type", func(t *testing.T) {
+		// given
+		blocks := []api.TextContent{api.Text("hello")}
+
+		// when
+		texts, err := api.DecodeContentBlocks(blocks)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, blocks, texts)
+	})
+
+	t.Run("decode a block of another type", func(t *testing.T) {
+		// given
+		blocks := []map[string]any{{"type": "text", "text": "hello"}}
+
+		// when
+		_, err := api.DecodeContentBlocks(blocks)
+
+		// then
+		require.EqualError(t, err, "invalid content block at index 0: api.TextContent is not a map[string]interface {}")
+	})
+
+	t.Run("resource link", func(t *testing.T) {
+		// when
+		link := api.NewResource("README", "file:///README.md").
+			WithTitle("Read me").
+			WithMimeType("text/markdown").
+			WithSize(42).
+			AsLink()
+
+		// then
+		actual, err := json.Marshal(link)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"type": "resource_link",
+			"uri": "file:///README.md",
+			"name": "README",
+			"title": "Read me",
+			"mimeType": "text/markdown",
+			"size": 42
+		}`, string(actual))
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		// when
+		_, err := api.UnmarshalContentBlock([]byte(`{"type": "video"}`))
+
+		// then
+		require.EqualError(t, err, "unknown content block type: 'video'")
+	})
+
+	t.Run("sampling content", func(t *testing.T) {
+		// given
+		msg := api.SamplingMessage{
+			Role:    api.RoleUser,
+			Content: api.Image([]byte("png"), "image/png").AsSamplingContent(),
+		}
+
+		// when
+		actual, err := json.Marshal(msg)
+
+		// then
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"role": "user", "content": {"type": "image", "data": "cG5n", "mimeType": "image/png"}}`, string(actual))
+		assert.Equal(t, api.Image([]byte("png"), "image/png"), msg.Content.ContentBlock())
+	})
+}
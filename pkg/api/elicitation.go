@@ -0,0 +1,157 @@
+package api
+
+import (
+	"maps"
+	"slices"
+)
+
+// PrimitiveSchema is the schema of a property of an elicitation schema: a `StringSchema`, `NumberSchema`,
+// `BooleanSchema` or `EnumSchema`
+type PrimitiveSchema interface {
+	primitiveSchema()
+}
+
+func (StringSchema) primitiveSchema()  {}
+func (NumberSchema) primitiveSchema()  {}
+func (BooleanSchema) primitiveSchema() {}
+func (EnumSchema) primitiveSchema()    {}
+
+// StringInput returns the schema of a string property
+func StringInput(title string) StringSchema {
+	return StringSchema{
+		Type:  TypeString,
+		Title: &title,
+	}
+}
+
+func (s StringSchema) WithDescription(description string) StringSchema {
+	s.Description = &description
+	return s
+}
+
+// WithFormat sets the format of the string (`date`, `date-time`, `email` or `uri`)
+func (s StringSchema) WithFormat(format StringSchemaFormat) StringSchema {
+	s.Format = &format
+	return s
+}
+
+func (s StringSchema) WithMinLength(length int) StringSchema {
+	s.MinLength = &length
+	return s
+}
+
+func (s StringSchema) WithMaxLength(length int) StringSchema {
+	s.MaxLength = &length
+	return s
+}
+
+// NumberInput returns the schema of a number property
+func NumberInput(title string) NumberSchema {
+	return NumberSchema{
+		Type:  NumberSchemaTypeNumber,
+		Title: &title,
+	}
+}
+
+// IntegerInput returns the schema of an integer property
+func IntegerInput(title string) NumberSchema {
+	return NumberSchema{
+		Type:  NumberSchemaTypeInteger,
+		Title: &title,
+	}
+}
+
+func (s NumberSchema) WithDescription(description string) NumberSchema {
+	s.Description = &description
+	return s
+}
+
+func (s NumberSchema) WithMinimum(minimum int) NumberSchema {
+	s.Minimum = &minimum
+	return s
+}
+
+func (s NumberSchema) WithMaximum(maximum int) NumberSchema {
+	s.Maximum = &maximum
+	return s
+}
+
+// BooleanInput returns the schema of a boolean property
+func BooleanInput(title string) BooleanSchema {
+	return BooleanSchema{
+		Type:  TypeBoolean,
+		Title: &title,
+	}
+}
+
+func (s BooleanSchema) WithDescription(description string) BooleanSchema {
+	s.Description = &description
+	return s
+}
+
+func (s BooleanSchema) WithDefault(value bool) BooleanSchema {
+	s.Default = &value
+	return s
+}
+
+// EnumInput returns the schema of a string property restricted to the given values
+func EnumInput(title string, values ...string) EnumSchema {
+	return EnumSchema{
+		Type:  TypeString,
+		Title: &title,
+		Enum:  values,
+	}
+}
+
+func (s EnumSchema) WithDescription(description string) EnumSchema {
+	s.Description = &description
+	return s
+}
+
+// WithNames sets the names to display for the values, in the same order
+func (s EnumSchema) WithNames(names ...string) EnumSchema {
+	s.EnumNames = names
+	return s
+}
+
+// ElicitationSchema builds the schema of the input requested from the user with an `elicitation/create` request.
+// The schema is limited to a flat object whose properties have a primitive type. Each method returns a new builder.
+type ElicitationSchema struct {
+	props    map[string]PrimitiveSchema
+	required []string
+}
+
+// NewElicitationSchema returns a builder for a schema without properties
+func NewElicitationSchema() ElicitationSchema {
+	return ElicitationSchema{
+		props:    map[string]PrimitiveSchema{},
+		required: []string{},
+	}
+}
+
+// WithProperty adds a property to the schema
+func (s ElicitationSchema) WithProperty(name string, prop PrimitiveSchema, required bool) ElicitationSchema {
+	s.props = maps.Clone(s.props)
+	if s.props == nil {
+		s.props = map[string]PrimitiveSchema{}
+	}
+	s.props[name] = prop
+	s.required = slices.DeleteFunc(slices.Clone(s.required), func(n string) bool { return n == name })
+	if required {
+		s.required = append(s.required, name)
+	}
+	return s
+}
+
+// Build returns the schema to send in the `elicitation/create` request
+func (s ElicitationSchema) Build() ElicitRequestParamsRequestedSchema {
+	props := make(map[string]any, len(s.props))
+	for name, p := range s.props {
+		props[name] = p
+	}
+	return ElicitRequestParamsRequestedSchema{
+		Type:       TypeObject,
+		Properties: props,
+		Required:   slices.Clone(s.required),
+	}
+}
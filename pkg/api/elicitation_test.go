@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicitationSchema(t *testing.T) {
+
+	// given
+	s := api.NewElicitationSchema().
+		WithProperty("name", api.StringInput("Name").WithDescription("your name").WithMinLength(1).WithMaxLength(40), true).
+		WithProperty("email", api.StringInput("Email").WithFormat(api.StringSchemaFormatEmail), false).
+		WithProperty("age", api.IntegerInput("Age").WithMinimum(0).WithMaximum(150), false).
+		WithProperty("ratio", api.NumberInput("Ratio"), false).
+		WithProperty("subscribe", api.BooleanInput("Subscribe").WithDefault(false), true).
+		WithProperty("plan", api.EnumInput("Plan", "free", "pro").WithNames("Free", "Pro"), true)
+
+	// when
+	actual, err := json.Marshal(s.Build())
+
+	// then
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "title": "Name", "description": "your name", "minLength": 1, "maxLength": 40},
+			"email": {"type": "string", "title": "Email", "format": "email"},
+			"age": {"type": "integer", "title": "Age", "minimum": 0, "maximum": 150},
+			"ratio": {"type": "number", "title": "Ratio"},
+			"subscribe": {"type": "boolean", "title": "Subscribe", "default": false},
+			"plan": {"type": "string", "title": "Plan", "enum": ["free", "pro"], "enumNames": ["Free", "Pro"]}
+		},
+		"required": ["name", "subscribe", "plan"]
+	}`, string(actual))
+
+	t.Run("builders are immutable", func(t *testing.T) {
+		// when
+		optional := s.WithProperty("name", api.StringInput("Name"), false)
+
+		// then
+		assert.NotContains(t, optional.Build().Required, "name")
+		assert.Contains(t, s.Build().Required, "name")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		// given
+		schema, err := api.CompileSchema(s.Build())
+		require.NoError(t, err)
+
+		// when
+		err1 := schema.Validate(map[string]any{"name": "John", "age": 42, "subscribe": true, "plan": "pro"})
+		err2 := schema.Validate(map[string]any{"name": "", "age": 200, "plan": "enterprise"})
+
+		// then
+		require.NoError(t, err1)
+		require.Error(t, err2)
+	})
+}
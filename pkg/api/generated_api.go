@@ -2457,10 +2457,6 @@ func (j *ProgressNotification) UnmarshalJSON(value []byte) error {
 	return nil
 }
 
-// A progress token, used to associate progress notifications with the original
-// request.
-type ProgressToken int
-
 // A prompt or prompt template that the server offers.
 type Prompt struct {
 	// See [specification/2025-06-18/basic/index#general-fields] for notes on _meta
@@ -2793,9 +2789,6 @@ type Request struct {
 	Params *RequestParams `json:"params,omitempty" yaml:"params,omitempty" mapstructure:"params,omitempty"`
 }
 
-// A uniquely identifying ID for a request in JSON-RPC.
-type RequestId int
-
 type RequestParams struct {
 	// See [specification/2025-06-18/basic/index#general-fields] for notes on _meta
 	// usage.
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// RequestId is a uniquely identifying ID for a request in JSON-RPC, which is either a string or an integer.
+// Values are comparable with `==` (a string ID is never equal to an integer ID), and can be used as map keys.
+//
+// This type replaces the generated one, which only supports integers.
+type RequestId struct {
+	stringOrInt
+}
+
+// StringRequestId returns a request ID in the string form
+func StringRequestId(id string) RequestId {
+	return RequestId{stringOrInt{str: id, isString: true}}
+}
+
+// IntRequestId returns a request ID in the integer form
+func IntRequestId(id int64) RequestId {
+	return RequestId{stringOrInt{num: id}}
+}
+
+// ProgressToken is a token used to associate progress notifications with the original request,
+// which is either a string or an integer.
+// Values are comparable with `==` (a string token is never equal to an integer token), and can be used as map keys.
+//
+// This type replaces the generated one, which only supports integers.
+type ProgressToken struct {
+	stringOrInt
+}
+
+// StringProgressToken returns a progress token in the string form
+func StringProgressToken(token string) ProgressToken {
+	return ProgressToken{stringOrInt{str: token, isString: true}}
+}
+
+// IntProgressToken returns a progress token in the integer form
+func IntProgressToken(token int64) ProgressToken {
+	return ProgressToken{stringOrInt{num: token}}
+}
+
+type stringOrInt struct {
+	str      string
+	num      int64
+	isString bool
+}
+
+// IsString returns true if the value is in the string form
+func (v stringOrInt) IsString() bool {
+	return v.isString
+}
+
+// Int returns the value in the integer form, and `false` if the value is in the string form
+func (v stringOrInt) Int() (int64, bool) {
+	return v.num, !v.isString
+}
+
+// String returns the value in the string form, or the decimal representation of the value in the integer form
+func (v stringOrInt) String() string {
+	if v.isString {
+		return v.str
+	}
+	return strconv.FormatInt(v.num, 10)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v stringOrInt) MarshalJSON() ([]byte, error) {
+	if v.isString {
+		return json.Marshal(v.str)
+	}
+	return []byte(strconv.FormatInt(v.num, 10)), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *stringOrInt) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = stringOrInt{str: s, isString: true}
+		return nil
+	}
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("expected a string or an integer, got '%s'", data)
+	}
+	*v = stringOrInt{num: n}
+	return nil
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestId(t *testing.T) {
+
+	t.Run("string", func(t *testing.T) {
+		// when
+		id := api.RequestId{}
+		err := json.Unmarshal([]byte(`"abc"`), &id)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, id.IsString())
+		assert.Equal(t, "abc", id.String())
+		assert.Equal(t, api.StringRequestId("abc"), id)
+		data, err := json.Marshal(id)
+		require.NoError(t, err)
+		assert.JSONEq(t, `"abc"`, string(data))
+	})
+
+	t.Run("integer", func(t *testing.T) {
+		// when
+		id := api.RequestId{}
+		err := json.Unmarshal([]byte(`42`), &id)
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, id.IsString())
+		n, ok := id.Int()
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), n)
+		assert.Equal(t, api.IntRequestId(42), id)
+		data, err := json.Marshal(id)
+		require.NoError(t, err)
+		assert.JSONEq(t, `42`, string(data))
+	})
+
+	t.Run("string and integer forms are not equal", func(t *testing.T) {
+		assert.NotEqual(t, api.StringRequestId("42"), api.IntRequestId(42))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, data := range []string{`1.5`, `true`, `null`, `{}`} {
+			// when
+			id := api.RequestId{}
+			err := json.Unmarshal([]byte(data), &id)
+
+			// then
+			require.EqualError(t, err, "expected a string or an integer, got '"+data+"'", data)
+		}
+	})
+}
+
+func TestProgressToken(t *testing.T) {
+
+	// given
+	params := api.ProgressNotificationParams{
+		ProgressToken: api.StringProgressToken("abc"),
+		Progress:      1,
+	}
+
+	// when
+	data, err := json.Marshal(params)
+
+	// then
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"progressToken":"abc","progress":1}`, string(data))
+	result := api.ProgressNotificationParams{}
+	err = json.Unmarshal(data, &result)
+	require.NoError(t, err)
+	assert.Equal(t, params, result)
+}
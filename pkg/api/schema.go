@@ -0,0 +1,273 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithInputSchemaFor sets the input schema of the tool from the type of the given value, which must be a struct
+// or a map with string keys (or nil, to accept any arguments). See `SchemaFor` for the supported types and struct tags.
+func (t Tool) WithInputSchemaFor(v any) Tool {
+	s := SchemaFor(v)
+	if s["type"] != TypeObject && v != nil {
+		panic(fmt.Sprintf("input of tool '%s' must be a struct or a map, not %T", t.Name, v))
+	}
+	t.InputSchema = ToolInputSchema{
+		Type:       TypeObject,
+		Properties: properties(s),
+		Required:   required(s),
+	}
+	return t
+}
+
+// WithOutputSchemaFor sets the output schema of the tool from the type of the given value.
+// Values which are not encoded as JSON objects are described in a required `result` property.
+// See `SchemaFor` for the supported types and struct tags.
+func (t Tool) WithOutputSchemaFor(v any) Tool {
+	s := SchemaFor(v)
+	if s["type"] != TypeObject {
+		s = map[string]any{
+			"type": TypeObject,
+			"properties": map[string]map[string]any{
+				"result": s,
+			},
+			"required": []string{"result"},
+		}
+	}
+	t.OutputSchema = &ToolOutputSchema{
+		Type:       TypeObject,
+		Properties: properties(s),
+		Required:   required(s),
+	}
+	return t
+}
+
+func properties(s map[string]any) map[string]map[string]any {
+	if props, ok := s["properties"].(map[string]map[string]any); ok {
+		return props
+	}
+	return map[string]map[string]any{}
+}
+
+func required(s map[string]any) []string {
+	if req, ok := s["required"].([]string); ok {
+		return req
+	}
+	return []string{}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// SchemaFor returns the JSON schema of the type of the given value, following the `encoding/json` rules:
+//   - booleans, integers, floats and strings are mapped to `boolean`, `integer`, `number` and `string`
+//   - `time.Time` is mapped to a `string` with the `date-time` format, and `[]byte` to a base64-encoded `string`
+//   - slices and arrays are mapped to `array`, with the schema of their elements in `items`
+//   - maps with string keys are mapped to `object`, with the schema of their values in `additionalProperties`
+//   - structs are mapped to `object`, with a property for each exported field (named after its `json` tag).
+//     Fields without the `omitempty` or `omitzero` option are required, except pointer fields which are optional.
+//   - pointers are mapped to the schema of the type they point to, and interfaces to an empty (any) schema
+//
+// The schema of a struct field can be refined with a `jsonschema` tag, containing a comma-separated list of:
+// `description=...`, `title=...`, `format=...`, `pattern=...`, `default=...`, `enum=...` (repeated for each value),
+// `minimum=...`, `maximum=...`, `exclusiveMinimum=...`, `exclusiveMaximum=...`, `minLength=...`, `maxLength=...`,
+// `minItems=...`, `maxItems=...`, `required` and `optional`. Commas in values must be escaped with a backslash.
+//
+// It panics if the type contains channels, funcs, complex numbers or maps with non-string keys.
+func SchemaFor(v any) map[string]any {
+	return schemaOf(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": TypeString, "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	case t.Implements(reflect.TypeFor[json.Marshaler]()) || reflect.PointerTo(t).Implements(reflect.TypeFor[json.Marshaler]()):
+		return map[string]any{} // the encoding is unknown
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": TypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": TypeNumber}
+	case reflect.String:
+		return map[string]any{"type": TypeString}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": TypeString, "contentEncoding": "base64"}
+		}
+		s := map[string]any{
+			"type":  TypeArray,
+			"items": schemaOf(t.Elem(), visiting),
+		}
+		if t.Kind() == reflect.Array {
+			s["minItems"] = t.Len()
+			s["maxItems"] = t.Len()
+		}
+		return s
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			panic(fmt.Sprintf("unsupported map key type in JSON schema: %s", t.Key()))
+		}
+		return map[string]any{
+			"type":                 TypeObject,
+			"additionalProperties": schemaOf(t.Elem(), visiting),
+		}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{"type": TypeObject} // recursive type
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		props := map[string]map[string]any{}
+		req := []string{}
+		addStructFields(t, props, &req, visiting)
+		return map[string]any{
+			"type":       TypeObject,
+			"properties": props,
+			"required":   req,
+		}
+	default:
+		panic(fmt.Sprintf("unsupported type in JSON schema: %s", t))
+	}
+}
+
+func addStructFields(t reflect.Type, props map[string]map[string]any, req *[]string, visiting map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// fields of embedded structs are promoted
+			addStructFields(ft, props, req, visiting)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := schemaOf(f.Type, visiting)
+		if hasOption(opts, "string") {
+			s = map[string]any{"type": TypeString}
+		}
+		// pointers are the usual way to declare optional fields
+		isRequired := !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero") && f.Type.Kind() != reflect.Pointer
+		for _, kv := range splitTag(f.Tag.Get("jsonschema")) {
+			key, value, _ := strings.Cut(kv, "=")
+			switch key {
+			case "required":
+				isRequired = true
+			case "optional":
+				isRequired = false
+			case "description", "title", "format", "pattern":
+				s[key] = value
+			case "default":
+				s[key] = parseValue(s, value, f)
+			case "enum":
+				enum, _ := s["enum"].([]any)
+				s["enum"] = append(enum, parseValue(s, value, f))
+			case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+				n, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					panic(fmt.Sprintf("invalid '%s' value in JSON schema of field '%s': %v", key, f.Name, err))
+				}
+				s[key] = n
+			case "minLength", "maxLength", "minItems", "maxItems":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					panic(fmt.Sprintf("invalid '%s' value in JSON schema of field '%s': %v", key, f.Name, err))
+				}
+				s[key] = n
+			default:
+				panic(fmt.Sprintf("unknown '%s' key in JSON schema of field '%s'", key, f.Name))
+			}
+		}
+		props[name] = s
+		if isRequired {
+			*req = append(*req, name)
+		}
+	}
+}
+
+func hasOption(opts, option string) bool {
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTag splits the given tag on the commas which are not escaped with a backslash
+func splitTag(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	result := []string{}
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			result = append(result, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	return append(result, current.String())
+}
+
+// parseValue parses the given value according to the type of the schema
+func parseValue(s map[string]any, value string, f reflect.StructField) any {
+	var (
+		v   any
+		err error
+	)
+	switch s["type"] {
+	case TypeBoolean:
+		v, err = strconv.ParseBool(value)
+	case TypeInteger:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case TypeNumber:
+		v, err = strconv.ParseFloat(value, 64)
+	case TypeString:
+		v = value
+	default:
+		err = json.Unmarshal([]byte(value), &v)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("invalid value '%s' in JSON schema of field '%s': %v", value, f.Name, err))
+	}
+	return v
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"maps"
+	"slices"
+)
+
+// SchemaBuilder builds a JSON schema. Each method returns a new builder, so that builders can be shared and reused.
+type SchemaBuilder struct {
+	schema map[string]any
+	props  map[string]SchemaBuilder
+	items  *SchemaBuilder
+	oneOf  []SchemaBuilder
+}
+
+func newSchemaBuilder(schemaType string) SchemaBuilder {
+	s := SchemaBuilder{
+		schema: map[string]any{},
+	}
+	if schemaType != "" {
+		s.schema["type"] = schemaType
+	}
+	return s
+}
+
+// String returns a builder for a `string` schema
+func String() SchemaBuilder {
+	return newSchemaBuilder(TypeString)
+}
+
+// Number returns a builder for a `number` schema
+func Number() SchemaBuilder {
+	return newSchemaBuilder(TypeNumber)
+}
+
+// Integer returns a builder for an `integer` schema
+func Integer() SchemaBuilder {
+	return newSchemaBuilder(TypeInteger)
+}
+
+// Boolean returns a builder for a `boolean` schema
+func Boolean() SchemaBuilder {
+	return newSchemaBuilder(TypeBoolean)
+}
+
+// Array returns a builder for an `array` schema whose items conform to the given schema
+func Array(items SchemaBuilder) SchemaBuilder {
+	s := newSchemaBuilder(TypeArray)
+	s.items = &items
+	return s
+}
+
+// Object returns a builder for an `object` schema, whose properties are added with `Prop`
+func Object() SchemaBuilder {
+	s := newSchemaBuilder(TypeObject)
+	s.props = map[string]SchemaBuilder{}
+	return s
+}
+
+// OneOf returns a builder for a schema which matches exactly one of the given schemas
+func OneOf(schemas ...SchemaBuilder) SchemaBuilder {
+	s := newSchemaBuilder("")
+	s.oneOf = schemas
+	return s
+}
+
+func (s SchemaBuilder) clone() SchemaBuilder {
+	s.schema = maps.Clone(s.schema)
+	s.props = maps.Clone(s.props)
+	s.oneOf = slices.Clone(s.oneOf)
+	return s
+}
+
+func (s SchemaBuilder) with(key string, value any) SchemaBuilder {
+	s = s.clone()
+	s.schema[key] = value
+	return s
+}
+
+func (s SchemaBuilder) Title(title string) SchemaBuilder {
+	return s.with("title", title)
+}
+
+func (s SchemaBuilder) Description(description string) SchemaBuilder {
+	return s.with("description", description)
+}
+
+// Enum restricts the values to the given ones
+func (s SchemaBuilder) Enum(values ...any) SchemaBuilder {
+	return s.with("enum", values)
+}
+
+func (s SchemaBuilder) Default(value any) SchemaBuilder {
+	return s.with("default", value)
+}
+
+// Format sets the format of a string (eg: `date-time`, `email`, `uri`)
+func (s SchemaBuilder) Format(format string) SchemaBuilder {
+	return s.with("format", format)
+}
+
+// Pattern sets the regular expression that a string must match
+func (s SchemaBuilder) Pattern(pattern string) SchemaBuilder {
+	return s.with("pattern", pattern)
+}
+
+func (s SchemaBuilder) MinLength(length int) SchemaBuilder {
+	return s.with("minLength", length)
+}
+
+func (s SchemaBuilder) MaxLength(length int) SchemaBuilder {
+	return s.with("maxLength", length)
+}
+
+func (s SchemaBuilder) Minimum(minimum float64) SchemaBuilder {
+	return s.with("minimum", minimum)
+}
+
+func (s SchemaBuilder) Maximum(maximum float64) SchemaBuilder {
+	return s.with("maximum", maximum)
+}
+
+func (s SchemaBuilder) ExclusiveMinimum(minimum float64) SchemaBuilder {
+	return s.with("exclusiveMinimum", minimum)
+}
+
+func (s SchemaBuilder) ExclusiveMaximum(maximum float64) SchemaBuilder {
+	return s.with("exclusiveMaximum", maximum)
+}
+
+func (s SchemaBuilder) MinItems(count int) SchemaBuilder {
+	return s.with("minItems", count)
+}
+
+func (s SchemaBuilder) MaxItems(count int) SchemaBuilder {
+	return s.with("maxItems", count)
+}
+
+// Prop adds a property to an object schema
+func (s SchemaBuilder) Prop(name string, prop SchemaBuilder) SchemaBuilder {
+	s = s.clone()
+	if s.props == nil {
+		s.props = map[string]SchemaBuilder{}
+	}
+	s.props[name] = prop
+	return s
+}
+
+// Required marks the given properties of an object schema as required
+func (s SchemaBuilder) Required(names ...string) SchemaBuilder {
+	required, _ := s.schema["required"].([]string)
+	return s.with("required", append(slices.Clone(required), names...))
+}
+
+// AdditionalProperties sets whether an object can have other properties than the ones declared with `Prop`
+func (s SchemaBuilder) AdditionalProperties(allowed bool) SchemaBuilder {
+	return s.with("additionalProperties", allowed)
+}
+
+// Build returns the JSON schema
+func (s SchemaBuilder) Build() map[string]any {
+	schema := maps.Clone(s.schema)
+	if s.props != nil {
+		props := make(map[string]map[string]any, len(s.props))
+		for name, p := range s.props {
+			props[name] = p.Build()
+		}
+		schema["properties"] = props
+	}
+	if s.items != nil {
+		schema["items"] = s.items.Build()
+	}
+	if s.oneOf != nil {
+		oneOf := make([]map[string]any, 0, len(s.oneOf))
+		for _, o := range s.oneOf {
+			oneOf = append(oneOf, o.Build())
+		}
+		schema["oneOf"] = oneOf
+	}
+	return schema
+}
+
+// WithInputObject sets the input schema of the tool from the properties of the given object schema
+func (t Tool) WithInputObject(s SchemaBuilder) Tool {
+	schema := s.Build()
+	t.InputSchema = ToolInputSchema{
+		Type:       TypeObject,
+		Properties: properties(schema),
+		Required:   required(schema),
+	}
+	return t
+}
+
+// WithOutputObject sets the output schema of the tool from the properties of the given object schema
+func (t Tool) WithOutputObject(s SchemaBuilder) Tool {
+	schema := s.Build()
+	t.OutputSchema = &ToolOutputSchema{
+		Type:       TypeObject,
+		Properties: properties(schema),
+		Required:   required(schema),
+	}
+	return t
+}
@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+
+	// given
+	tag := api.String().Enum("bug", "feature").Description("a tag")
+
+	// when
+	s := api.Object().
+		Prop("title", api.String().MinLength(1).MaxLength(80)).
+		Prop("tags", api.Array(tag).MaxItems(3)).
+		Prop("priority", api.Integer().Minimum(1).Maximum(5).Default(3)).
+		Prop("due", api.String().Format("date")).
+		Prop("assignee", api.OneOf(
+			api.String().Pattern("^@"),
+			api.Object().Prop("email", api.String().Format("email")).Required("email"),
+		)).
+		Required("title", "tags")
+
+	// then
+	expected := `{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string", "minLength": 1, "maxLength": 80},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["bug", "feature"], "description": "a tag"}, "maxItems": 3},
+			"priority": {"type": "integer", "minimum": 1, "maximum": 5, "default": 3},
+			"due": {"type": "string", "format": "date"},
+			"assignee": {
+				"oneOf": [
+					{"type": "string", "pattern": "^@"},
+					{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}, "required": ["email"]}
+				]
+			}
+		},
+		"required": ["title", "tags"]
+	}`
+	actual, err := json.Marshal(s.Build())
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+
+	t.Run("validation", func(t *testing.T) {
+		// given
+		schema, err := api.CompileSchema(s.Build())
+		require.NoError(t, err)
+
+		// when
+		err1 := schema.Validate(map[string]any{"title": "t", "tags": []string{"bug"}, "assignee": "@me"})
+		err2 := schema.Validate(map[string]any{"title": "t", "tags": []string{"chore"}, "assignee": "me"})
+
+		// then
+		require.NoError(t, err1)
+		require.EqualError(t, err2, `'assignee' must match exactly one of the 2 allowed schemas; 'tags[0]' must be one of "bug", "feature"`)
+	})
+
+	t.Run("builders are immutable", func(t *testing.T) {
+		// when
+		base := api.Object().Prop("a", api.String())
+		extended := base.Prop("b", api.String()).Required("b")
+
+		// then
+		assert.Len(t, base.Build()["properties"], 1)
+		assert.NotContains(t, base.Build(), "required")
+		assert.Len(t, extended.Build()["properties"], 2)
+	})
+
+	t.Run("tool", func(t *testing.T) {
+		// when
+		tool := api.NewTool("my-tool").
+			WithInputObject(api.Object().Prop("query", api.String()).Required("query")).
+			WithOutputProp("count", api.Integer().Minimum(0), true)
+
+		// then
+		assert.Equal(t, api.ToolInputSchema{
+			Type:       "object",
+			Properties: map[string]map[string]any{"query": {"type": "string"}},
+			Required:   []string{"query"},
+		}, tool.InputSchema)
+		assert.Equal(t, &api.ToolOutputSchema{
+			Type:       "object",
+			Properties: map[string]map[string]any{"count": {"type": "integer", "minimum": float64(0)}},
+			Required:   []string{"count"},
+		}, tool.OutputSchema)
+	})
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	Street string `json:"street" jsonschema:"description=the street\\, including the number"`
+	City   string `json:"city"`
+}
+
+type Base struct {
+	ID string `json:"id" jsonschema:"format=uuid"`
+}
+
+type Person struct {
+	Base
+	Name      string            `json:"name" jsonschema:"description=the name of the person,minLength=1"`
+	Age       int               `json:"age,omitempty" jsonschema:"minimum=0,maximum=150,default=18"`
+	Role      string            `json:"role" jsonschema:"enum=admin,enum=user,optional"`
+	Addresses []Address         `json:"addresses,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Birthdate *time.Time        `json:"birthdate,omitempty"`
+	Manager   *Person           `json:"manager,omitempty"`
+	Ignored   string            `json:"-"`
+	internal  string            //nolint:unused
+}
+
+func TestSchemaFor(t *testing.T) {
+
+	// when
+	s := api.SchemaFor(Person{})
+
+	// then
+	expected := `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"name": {"type": "string", "description": "the name of the person", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0, "maximum": 150, "default": 18},
+			"role": {"type": "string", "enum": ["admin", "user"]},
+			"addresses": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"street": {"type": "string", "description": "the street, including the number"},
+						"city": {"type": "string"}
+					},
+					"required": ["street", "city"]
+				}
+			},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"birthdate": {"type": "string", "format": "date-time"},
+			"manager": {"type": "object"}
+		},
+		"required": ["id", "name"]
+	}`
+	actual, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+}
+
+func TestSchemaForPointerFields(t *testing.T) {
+
+	// given
+	type Query struct {
+		Text  string  `json:"text"`
+		Limit *int    `json:"limit"`
+		Owner *string `json:"owner" jsonschema:"required"`
+	}
+
+	// when
+	s := api.SchemaFor(Query{})
+
+	// then
+	expected := `{
+		"type": "object",
+		"properties": {
+			"text": {"type": "string"},
+			"limit": {"type": "integer"},
+			"owner": {"type": "string"}
+		},
+		"required": ["text", "owner"]
+	}`
+	actual, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+}
+
+func TestToolSchemaFor(t *testing.T) {
+
+	t.Run("input and output", func(t *testing.T) {
+		// when
+		tool := api.NewTool("my-tool").WithInputSchemaFor(Address{}).WithOutputSchemaFor(&Base{})
+
+		// then
+		assert.Equal(t, api.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]map[string]any{
+				"street": {"type": "string", "description": "the street, including the number"},
+				"city":   {"type": "string"},
+			},
+			Required: []string{"street", "city"},
+		}, tool.InputSchema)
+		assert.Equal(t, &api.ToolOutputSchema{
+			Type: "object",
+			Properties: map[string]map[string]any{
+				"id": {"type": "string", "format": "uuid"},
+			},
+			Required: []string{"id"},
+		}, tool.OutputSchema)
+	})
+
+	t.Run("non-object output", func(t *testing.T) {
+		// when
+		tool := api.NewTool("my-tool").WithOutputSchemaFor([]int{})
+
+		// then
+		assert.Equal(t, &api.ToolOutputSchema{
+			Type: "object",
+			Properties: map[string]map[string]any{
+				"result": {"type": "array", "items": map[string]any{"type": "integer"}},
+			},
+			Required: []string{"result"},
+		}, tool.OutputSchema)
+	})
+
+	t.Run("non-object input", func(t *testing.T) {
+		assert.PanicsWithValue(t, "input of tool 'my-tool' must be a struct or a map, not string", func() {
+			api.NewTool("my-tool").WithInputSchemaFor("")
+		})
+	})
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"maps"
+	"slices"
+)
+
+// NewTool returns a tool without output schema, ie: whose results are not expected to have structured content
 func NewTool(name string) Tool {
 	return Tool{
 		Name:        name,
@@ -9,37 +15,61 @@ func NewTool(name string) Tool {
 			Properties: map[string]map[string]any{},
 			Required:   []string{},
 		},
-		OutputSchema: &ToolOutputSchema{
-			Type:       "object",
-			Properties: map[string]map[string]any{},
-		},
 	}
 }
 
-const String = "string"
+// JSON schema types
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
 
 func (t Tool) WithInputProperty(name string, propType string, description string, required bool) Tool {
-	t.InputSchema.Properties[name] = map[string]any{
-		"type":        propType,
-		"description": description,
-	}
-	if required {
-		t.InputSchema.Required = append(t.InputSchema.Required, name)
-	}
+	return t.WithInputProp(name, newSchemaBuilder(propType).Description(description), required)
+}
+
+// WithInputProp adds a property to the input schema of the tool
+func (t Tool) WithInputProp(name string, prop SchemaBuilder, required bool) Tool {
+	t.InputSchema.Properties, t.InputSchema.Required = withProp(t.InputSchema.Properties, t.InputSchema.Required, name, prop, required)
 	return t
 }
 
 func (t Tool) WithOutputProperty(name string, propType string, description string, required bool) Tool {
-	t.OutputSchema.Properties[name] = map[string]any{
-		"type":        propType,
-		"description": description,
-	}
-	if required {
-		t.OutputSchema.Required = append(t.OutputSchema.Required, name)
+	return t.WithOutputProp(name, newSchemaBuilder(propType).Description(description), required)
+}
+
+// WithOutputProp adds a property to the output schema of the tool
+func (t Tool) WithOutputProp(name string, prop SchemaBuilder, required bool) Tool {
+	s := newToolOutputSchema(nil)
+	if t.OutputSchema != nil {
+		*s = *t.OutputSchema
 	}
+	s.Properties, s.Required = withProp(s.Properties, s.Required, name, prop, required)
+	t.OutputSchema = s
 	return t
 }
 
+// withProp returns copies of the given properties and required names, with the given property
+func withProp(props map[string]map[string]any, required []string, name string, prop SchemaBuilder, isRequired bool) (map[string]map[string]any, []string) {
+	props = maps.Clone(props)
+	if props == nil {
+		props = map[string]map[string]any{}
+	}
+	props[name] = prop.Build()
+	required = slices.Clone(required)
+	if required == nil {
+		required = []string{}
+	}
+	if isRequired {
+		required = append(required, name)
+	}
+	return props, required
+}
+
 func (t Tool) WithMetadata(metadata map[string]any) Tool {
 	t.Meta = metadata
 	return t
@@ -77,25 +107,18 @@ func (t Tool) WithOutputSchema(outputProps ...PropertyDefinition) Tool {
 
 type PropertyDefinition struct {
 	Name        string `json:"name"`
-	Type        string `json:"type"` // TODO: enum
+	Type        string `json:"type"` // one of the `Type*` constants
 	Description string `json:"description"`
 	Required    bool   `json:"required"`
 }
 
 func newToolOutputSchema(properties []PropertyDefinition) *ToolOutputSchema {
-	s := &ToolOutputSchema{
-		Type:       "object",
-		Properties: map[string]map[string]any{},
-		Required:   []string{},
-	}
+	s := Object()
 	for _, p := range properties {
-		s.Properties[p.Name] = map[string]any{
-			"type":        p.Type,
-			"description": p.Description,
-		}
+		s = s.Prop(p.Name, newSchemaBuilder(p.Type).Description(p.Description))
 		if p.Required {
-			s.Required = append(s.Required, p.Name)
+			s = s.Required(p.Name)
 		}
 	}
-	return s
+	return Tool{}.WithOutputObject(s).OutputSchema
 }
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError lists the reasons why a value does not conform to a JSON schema
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Errors, "; ")
+}
+
+// Schema is a compiled JSON schema, which supports the following subset of the 2020-12 draft:
+// `type`, `enum`, `const`, `minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`, `minLength`, `maxLength`,
+// `pattern`, `items`, `minItems`, `maxItems`, `properties`, `required`, `additionalProperties`, `oneOf` and `anyOf`.
+// Other keywords are ignored.
+type Schema struct {
+	schema   map[string]any
+	patterns map[string]*regexp.Regexp
+}
+
+// CompileSchema compiles the given JSON schema, which can be any value encoded as a JSON object
+// (eg: a `ToolInputSchema` or a `map[string]any`)
+func CompileSchema(schema any) (*Schema, error) {
+	s := &Schema{
+		patterns: map[string]*regexp.Regexp{},
+	}
+	if err := normalize(schema, &s.schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.compilePatterns(s.schema); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) compilePatterns(schema map[string]any) error {
+	if pattern, ok := schema["pattern"].(string); ok {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema: invalid pattern '%s': %w", pattern, err)
+		}
+		s.patterns[pattern] = r
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		if err := s.compilePatterns(items); err != nil {
+			return err
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+		if err := s.compilePatterns(additional); err != nil {
+			return err
+		}
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if schemas, ok := schema[keyword].([]any); ok {
+			for _, o := range schemas {
+				if o, ok := o.(map[string]any); ok {
+					if err := s.compilePatterns(o); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for _, p := range props {
+			if p, ok := p.(map[string]any); ok {
+				if err := s.compilePatterns(p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the given value, once encoded in JSON, conforms to the schema.
+// It returns a `*ValidationError` listing all the violations otherwise.
+func (s *Schema) Validate(value any) error {
+	var v any
+	if err := normalize(value, &v); err != nil {
+		return &ValidationError{Errors: []string{err.Error()}}
+	}
+	errs := []string{}
+	s.validate(s.schema, v, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// normalize converts the given value into its generic JSON representation
+func normalize(value any, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (s *Schema) validate(schema map[string]any, v any, path string, errs *[]string) {
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		*errs = append(*errs, fmt.Sprintf("%s must be of type %s, not %s", describe(path), typeNames(t), jsonType(v)))
+		return // other checks are irrelevant
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		values := make([]string, 0, len(enum))
+		for _, e := range enum {
+			data, _ := json.Marshal(e)
+			values = append(values, string(data))
+		}
+		*errs = append(*errs, fmt.Sprintf("%s must be one of %s", describe(path), strings.Join(values, ", ")))
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok && s.countMatches(oneOf, v) != 1 {
+		*errs = append(*errs, fmt.Sprintf("%s must match exactly one of the %d allowed schemas", describe(path), len(oneOf)))
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && s.countMatches(anyOf, v) == 0 {
+		*errs = append(*errs, fmt.Sprintf("%s must match at least one of the %d allowed schemas", describe(path), len(anyOf)))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		data, _ := json.Marshal(c)
+		*errs = append(*errs, fmt.Sprintf("%s must be equal to %s", describe(path), data))
+	}
+	switch v := v.(type) {
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			*errs = append(*errs, fmt.Sprintf("%s must be greater than or equal to %v", describe(path), min))
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			*errs = append(*errs, fmt.Sprintf("%s must be less than or equal to %v", describe(path), max))
+		}
+		if min, ok := schema["exclusiveMinimum"].(float64); ok && v <= min {
+			*errs = append(*errs, fmt.Sprintf("%s must be greater than %v", describe(path), min))
+		}
+		if max, ok := schema["exclusiveMaximum"].(float64); ok && v >= max {
+			*errs = append(*errs, fmt.Sprintf("%s must be less than %v", describe(path), max))
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := schema["minLength"].(float64); ok && float64(length) < min {
+			*errs = append(*errs, fmt.Sprintf("%s must be at least %v characters long", describe(path), min))
+		}
+		if max, ok := schema["maxLength"].(float64); ok && float64(length) > max {
+			*errs = append(*errs, fmt.Sprintf("%s must be at most %v characters long", describe(path), max))
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !s.patterns[pattern].MatchString(v) {
+			*errs = append(*errs, fmt.Sprintf("%s must match the '%s' pattern", describe(path), pattern))
+		}
+	case []any:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			*errs = append(*errs, fmt.Sprintf("%s must contain at least %v items", describe(path), min))
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			*errs = append(*errs, fmt.Sprintf("%s must contain at most %v items", describe(path), max))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				s.validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, found := v[name]; !found {
+						*errs = append(*errs, fmt.Sprintf("%s is required", describe(join(path, name))))
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names) // report the errors in a stable order
+		for _, name := range names {
+			if p, ok := props[name].(map[string]any); ok {
+				s.validate(p, v[name], join(path, name), errs)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*errs = append(*errs, fmt.Sprintf("%s is not allowed", describe(join(path, name))))
+				}
+			case map[string]any:
+				s.validate(additional, v[name], join(path, name), errs)
+			}
+		}
+	}
+}
+
+// countMatches returns the number of the given schemas which the given value conforms to
+func (s *Schema) countMatches(schemas []any, v any) int {
+	count := 0
+	for _, schema := range schemas {
+		if schema, ok := schema.(map[string]any); ok {
+			errs := []string{}
+			if s.validate(schema, v, "", &errs); len(errs) == 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describe(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return fmt.Sprintf("'%s'", path)
+}
+
+func matchesType(t any, v any) bool {
+	switch t := t.(type) {
+	case string:
+		return t == jsonType(v) || (t == TypeNumber && jsonType(v) == TypeInteger)
+	case []any:
+		return slices.ContainsFunc(t, func(t any) bool { return matchesType(t, v) })
+	default:
+		return true
+	}
+}
+
+func typeNames(t any) string {
+	if types, ok := t.([]any); ok {
+		names := make([]string, 0, len(types))
+		for _, t := range types {
+			names = append(names, fmt.Sprint(t))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return TypeBoolean
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return TypeInteger
+		}
+		return TypeNumber
+	case string:
+		return TypeString
+	case []any:
+		return TypeArray
+	case map[string]any:
+		return TypeObject
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaValidate(t *testing.T) {
+
+	// given
+	schema, err := api.CompileSchema(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":    map[string]any{"type": "string", "pattern": "^[a-z]+$", "maxLength": 4},
+			"score": map[string]any{"type": "number", "exclusiveMaximum": 1},
+			"tags": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"type": "string", "minLength": 1},
+				"maxItems": 2,
+			},
+			"owner": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": []string{"string", "null"}},
+				},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+		},
+		"required": []string{"id"},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		value          any
+		expectedErrors []string
+	}{
+		{
+			name: "valid",
+			value: map[string]any{
+				"id":    "abc",
+				"score": 0.5,
+				"tags":  []string{"a", "b"},
+				"owner": map[string]any{"name": nil},
+			},
+		},
+		{
+			name: "invalid",
+			value: map[string]any{
+				"id":    "ABCDE",
+				"score": 1,
+				"tags":  []string{"a", "", "c"},
+				"owner": map[string]any{"age": 1},
+			},
+			expectedErrors: []string{
+				"'id' must be at most 4 characters long",
+				"'id' must match the '^[a-z]+$' pattern",
+				"'owner.name' is required",
+				"'owner.age' is not allowed",
+				"'score' must be less than 1",
+				"'tags' must contain at most 2 items",
+				"'tags[1]' must be at least 1 characters long",
+			},
+		},
+		{
+			name:           "invalid type",
+			value:          []string{},
+			expectedErrors: []string{"value must be of type object, not array"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// when
+			err := schema.Validate(testCase.value)
+
+			// then
+			if testCase.expectedErrors == nil {
+				require.NoError(t, err)
+				return
+			}
+			var validationErr *api.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, testCase.expectedErrors, validationErr.Errors)
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		// when
+		_, err := api.CompileSchema(map[string]any{"type": "string", "pattern": "["})
+
+		// then
+		require.ErrorContains(t, err, "invalid schema: invalid pattern '['")
+	})
+}
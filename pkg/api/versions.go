@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Protocol revisions supported by this package.
+// The types of this package are generated from the schema of the latest revision only. Since the results of the
+// supported revisions only differ by the fields and content blocks which were added over time, the results are
+// converted for the clients using an older revision by stripping them with `ConvertResult`.
+const (
+	ProtocolVersion20241105 = "2024-11-05"
+	ProtocolVersion20250326 = "2025-03-26"
+	ProtocolVersion20250618 = "2025-06-18"
+	LatestProtocolVersion   = ProtocolVersion20250618
+)
+
+// SupportedProtocolVersions returns the supported protocol revisions, from the oldest to the latest
+func SupportedProtocolVersions() []string {
+	return []string{ProtocolVersion20241105, ProtocolVersion20250326, ProtocolVersion20250618}
+}
+
+// NegotiateProtocolVersion returns the version requested by the client if it is supported,
+// or the latest version otherwise (in which case the client should disconnect if it does not support it)
+func NegotiateProtocolVersion(requested string) string {
+	if slices.Contains(SupportedProtocolVersions(), requested) {
+		return requested
+	}
+	return LatestProtocolVersion
+}
+
+// ConvertResult strips the fields and the content blocks which are unknown to the given revision from the given result
+// (of the latest revision). It does not model the types of the older revisions, so it only supports revisions whose
+// results differ by additions, and a new revision which renames or restructures a field needs its own types.
+// The stripped fields and content blocks are:
+//   - before 2025-06-18: the `title` of the tools, prompts, resources and implementations, the `_meta` of the tools,
+//     the `outputSchema` of the tools, the `structuredContent` of the tool results and the `resource_link` content blocks
+//   - before 2025-03-26: the `annotations` of the tools, the `completions` capability and the `audio` content blocks
+//
+// When the structured content of a tool result is removed and no other content remains, it is converted
+// into a text block. The results of an older revision are valid results of the newer ones, so they need no conversion.
+// The given result is not modified, and unknown result types are returned as-is.
+func ConvertResult(result any, version string) any {
+	if version == "" || version >= LatestProtocolVersion {
+		return result
+	}
+	switch r := result.(type) {
+	case *InitializeResult:
+		return convertInitializeResult(*r, version)
+	case InitializeResult:
+		return convertInitializeResult(r, version)
+	case *ListToolsResult:
+		return convertListToolsResult(*r, version)
+	case ListToolsResult:
+		return convertListToolsResult(r, version)
+	case *CallToolResult:
+		return convertCallToolResult(*r, version)
+	case CallToolResult:
+		return convertCallToolResult(r, version)
+	case *ListPromptsResult:
+		return convertListPromptsResult(*r)
+	case ListPromptsResult:
+		return convertListPromptsResult(r)
+	case *GetPromptResult:
+		return convertGetPromptResult(*r, version)
+	case GetPromptResult:
+		return convertGetPromptResult(r, version)
+	case *ListResourcesResult:
+		return convertListResourcesResult(*r)
+	case ListResourcesResult:
+		return convertListResourcesResult(r)
+	default:
+		return result
+	}
+}
+
+func convertInitializeResult(r InitializeResult, version string) InitializeResult {
+	r.ServerInfo.Title = nil
+	if version < ProtocolVersion20250326 {
+		r.Capabilities.Completions = nil
+	}
+	return r
+}
+
+func convertListToolsResult(r ListToolsResult, version string) ListToolsResult {
+	tools := make([]Tool, 0, len(r.Tools))
+	for _, t := range r.Tools {
+		t.Title = nil
+		t.Meta = nil
+		t.OutputSchema = nil
+		if version < ProtocolVersion20250326 {
+			t.Annotations = nil
+		}
+		tools = append(tools, t)
+	}
+	r.Tools = tools
+	return r
+}
+
+func convertCallToolResult(r CallToolResult, version string) CallToolResult {
+	content := make([]CallToolResultContentElem, 0, len(r.Content))
+	for _, c := range r.Content {
+		if isSupportedContent(c, version) {
+			content = append(content, c)
+		}
+	}
+	if len(content) == 0 && r.StructuredContent != nil {
+		if data, err := json.Marshal(r.StructuredContent); err == nil {
+			content = append(content, Text(string(data)))
+		}
+	}
+	r.Content = content
+	r.StructuredContent = nil
+	return r
+}
+
+func convertListPromptsResult(r ListPromptsResult) ListPromptsResult {
+	prompts := make([]Prompt, 0, len(r.Prompts))
+	for _, p := range r.Prompts {
+		p.Title = nil
+		if p.Arguments != nil {
+			args := make([]PromptArgument, 0, len(p.Arguments))
+			for _, a := range p.Arguments {
+				a.Title = nil
+				args = append(args, a)
+			}
+			p.Arguments = args
+		}
+		prompts = append(prompts, p)
+	}
+	r.Prompts = prompts
+	return r
+}
+
+func convertGetPromptResult(r GetPromptResult, version string) GetPromptResult {
+	messages := make([]PromptMessage, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		if isSupportedContent(m.Content, version) {
+			messages = append(messages, m)
+		}
+	}
+	r.Messages = messages
+	return r
+}
+
+func convertListResourcesResult(r ListResourcesResult) ListResourcesResult {
+	resources := make([]Resource, 0, len(r.Resources))
+	for _, res := range r.Resources {
+		res.Title = nil
+		resources = append(resources, res)
+	}
+	r.Resources = resources
+	return r
+}
+
+// isSupportedContent returns true if the type of the given content block exists in the given revision
+func isSupportedContent(block any, version string) bool {
+	c, err := DecodeContentBlock(block)
+	if err != nil {
+		return true // leave it to the client
+	}
+	switch c.(type) {
+	case ResourceLink:
+		return version >= ProtocolVersion20250618
+	case AudioContent:
+		return version >= ProtocolVersion20250326
+	default:
+		return true
+	}
+}
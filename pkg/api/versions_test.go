@@ -0,0 +1,241 @@
+package api_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	assert.Equal(t, api.ProtocolVersion20241105, api.NegotiateProtocolVersion("2024-11-05"))
+	assert.Equal(t, api.ProtocolVersion20250326, api.NegotiateProtocolVersion("2025-03-26"))
+	assert.Equal(t, api.LatestProtocolVersion, api.NegotiateProtocolVersion("2099-01-01"))
+	assert.Equal(t, api.LatestProtocolVersion, api.NegotiateProtocolVersion(""))
+}
+
+func TestConvertResult(t *testing.T) {
+
+	t.Run("list tools", func(t *testing.T) {
+		// given
+		result := &api.ListToolsResult{
+			Tools: []api.Tool{
+				api.NewTool("search").
+					WithTitle("Search").
+					WithReadOnlyHint(true).
+					WithOutputProperty("count", api.TypeInteger, "the number of matches", true),
+			},
+		}
+
+		t.Run("latest", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(result, api.LatestProtocolVersion)
+
+			// then
+			assert.Same(t, result, converted)
+		})
+
+		t.Run("2025-03-26", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(result, api.ProtocolVersion20250326)
+
+			// then
+			expected := api.NewTool("search").WithReadOnlyHint(true)
+			assert.Equal(t, api.ListToolsResult{Tools: []api.Tool{expected}}, converted)
+			assert.NotNil(t, result.Tools[0].Title) // not modified
+		})
+
+		t.Run("2024-11-05", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(result, api.ProtocolVersion20241105)
+
+			// then
+			expected := api.NewTool("search")
+			expected.Annotations = nil
+			assert.Equal(t, api.ListToolsResult{Tools: []api.Tool{expected}}, converted)
+		})
+	})
+
+	t.Run("call tool", func(t *testing.T) {
+		// given
+		result := api.CallToolResult{
+			Content: []api.CallToolResultContentElem{
+				api.Text("found 1 match"),
+				api.NewResource("match.txt", "file:///match.txt").AsLink(),
+				api.Audio([]byte("audio"), "audio/wav"),
+			},
+			StructuredContent: map[string]any{"count": 1},
+		}
+
+		t.Run("2025-03-26", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(result, api.ProtocolVersion20250326)
+
+			// then
+			assert.Equal(t, api.CallToolResult{
+				Content: []api.CallToolResultContentElem{
+					api.Text("found 1 match"),
+					api.Audio([]byte("audio"), "audio/wav"),
+				},
+			}, converted)
+		})
+
+		t.Run("2024-11-05", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(result, api.ProtocolVersion20241105)
+
+			// then
+			assert.Equal(t, api.CallToolResult{
+				Content: []api.CallToolResultContentElem{
+					api.Text("found 1 match"),
+				},
+			}, converted)
+		})
+
+		t.Run("structured content only", func(t *testing.T) {
+			// when
+			converted := api.ConvertResult(api.CallToolResult{
+				Content:           []api.CallToolResultContentElem{},
+				StructuredContent: map[string]any{"count": 1},
+			}, api.ProtocolVersion20250326)
+
+			// then
+			assert.Equal(t, api.CallToolResult{
+				Content: []api.CallToolResultContentElem{
+					api.Text(`{"count":1}`),
+				},
+			}, converted)
+		})
+	})
+
+	t.Run("get prompt", func(t *testing.T) {
+		// when
+		converted := api.ConvertResult(api.GetPromptResult{
+			Messages: []api.PromptMessage{
+				{Role: api.RoleUser, Content: api.Text("hello")},
+				{Role: api.RoleUser, Content: api.NewResource("hello.txt", "file:///hello.txt").AsLink()},
+			},
+		}, api.ProtocolVersion20250326)
+
+		// then
+		assert.Equal(t, api.GetPromptResult{
+			Messages: []api.PromptMessage{
+				{Role: api.RoleUser, Content: api.Text("hello")},
+			},
+		}, converted)
+	})
+
+	t.Run("unknown result", func(t *testing.T) {
+		// when
+		converted := api.ConvertResult(api.ReadResourceResult{}, api.ProtocolVersion20241105)
+
+		// then
+		assert.Equal(t, api.ReadResourceResult{}, converted)
+	})
+}
+
+func TestConvertResultFields(t *testing.T) {
+
+	// given
+	prompt := api.NewPrompt("summarize").WithTitle("Summarize").WithArgument("text", "Text", "the text", true)
+	testCases := []struct {
+		field  string
+		result any
+		since  string
+	}{
+		{
+			field:  "serverInfo.title",
+			result: api.InitializeResult{ServerInfo: api.Implementation{Name: "converse-mcp", Title: api.StringPtr("Converse")}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "capabilities.completions",
+			result: api.InitializeResult{Capabilities: api.ServerCapabilities{Completions: map[string]any{"enabled": true}}},
+			since:  api.ProtocolVersion20250326,
+		},
+		{
+			field:  "tools.0.title",
+			result: api.ListToolsResult{Tools: []api.Tool{api.NewTool("search").WithTitle("Search")}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "tools.0._meta",
+			result: api.ListToolsResult{Tools: []api.Tool{api.NewTool("search").WithMetadata(map[string]any{"k": "v"})}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "tools.0.outputSchema",
+			result: api.ListToolsResult{Tools: []api.Tool{api.NewTool("search").WithOutputProperty("count", api.TypeInteger, "", true)}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "tools.0.annotations",
+			result: api.ListToolsResult{Tools: []api.Tool{api.NewTool("search").WithReadOnlyHint(true)}},
+			since:  api.ProtocolVersion20250326,
+		},
+		{
+			field:  "structuredContent",
+			result: api.CallToolResult{Content: []api.CallToolResultContentElem{}, StructuredContent: map[string]any{"count": 1}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "prompts.0.title",
+			result: api.ListPromptsResult{Prompts: []api.Prompt{prompt}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "prompts.0.arguments.0.title",
+			result: api.ListPromptsResult{Prompts: []api.Prompt{prompt}},
+			since:  api.ProtocolVersion20250618,
+		},
+		{
+			field:  "resources.0.title",
+			result: api.ListResourcesResult{Resources: []api.Resource{api.NewResource("README", "file:///README.md").WithTitle("Read me")}},
+			since:  api.ProtocolVersion20250618,
+		},
+	}
+
+	for _, testCase := range testCases {
+		for _, version := range api.SupportedProtocolVersions() {
+			t.Run(testCase.field+" in "+version, func(t *testing.T) {
+				// when
+				converted := api.ConvertResult(testCase.result, version)
+
+				// then
+				data, err := json.Marshal(converted)
+				require.NoError(t, err)
+				var value any
+				require.NoError(t, json.Unmarshal(data, &value))
+				assert.Equal(t, version >= testCase.since, hasField(value, testCase.field))
+			})
+		}
+	}
+}
+
+// hasField checks if the given decoded JSON value has a field at the given dot-separated path
+// (in which array elements are designated by their index)
+func hasField(value any, path string) bool {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var found bool
+			if value, found = v[key]; !found {
+				return false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i >= len(v) {
+				return false
+			}
+			value = v[i]
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,188 @@
 package channel
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	jrpc2channel "github.com/creachadair/jrpc2/channel"
 )
 
 type Channel = jrpc2channel.Channel
 
-var StdioChannel Channel = jrpc2channel.Line(os.Stdin, os.Stdout)
+// Framing defines how messages are delimited on a stream
+type Framing int
+
+const (
+	// LineFraming delimits messages with a newline, as specified by the MCP stdio transport
+	LineFraming Framing = iota
+	// HeaderFraming prefixes messages with a `Content-Length` header, as specified by the Language Server Protocol
+	HeaderFraming
+)
+
+// DefaultMaxMessageSize is the default maximum size of a received message, in bytes
+const DefaultMaxMessageSize = 4 << 20
+
+// MessageTooLargeError is returned when a received message exceeds the maximum size
+type MessageTooLargeError struct {
+	MaxSize int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message exceeds the maximum size of %d bytes", e.MaxSize)
+}
+
+// StdioChannel receives messages from the standard input and sends messages on the standard output, delimited with
+// a newline.
+//
+// Deprecated: use channel.Stdio
+var StdioChannel Channel = Stdio(LineFraming, DefaultMaxMessageSize)
+
+// Stdio returns a channel which receives messages from the standard input and sends messages on the standard output
+func Stdio(framing Framing, maxSize int) Channel {
+	return New(os.Stdin, os.Stdout, framing, maxSize)
+}
+
+// New returns a channel which receives messages from the given reader and sends messages on the given writer,
+// using the given framing. Received messages larger than the given size (or `DefaultMaxMessageSize` if not positive)
+// result in a `MessageTooLargeError`.
+func New(r io.Reader, wc io.WriteCloser, framing Framing, maxSize int) Channel {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	if framing == HeaderFraming {
+		return &header{
+			r:       bufio.NewReader(r),
+			wc:      wc,
+			maxSize: maxSize,
+		}
+	}
+	return &line{
+		r:       bufio.NewReader(r),
+		wc:      wc,
+		maxSize: maxSize,
+	}
+}
 
 func Direct() (Channel, Channel) {
 	return jrpc2channel.Direct()
 }
+
+type line struct {
+	r       *bufio.Reader
+	wc      io.WriteCloser
+	maxSize int
+}
+
+func (c *line) Send(msg []byte) error {
+	if bytes.IndexByte(msg, '\n') >= 0 {
+		return errors.New("message contains a newline")
+	}
+	_, err := c.wc.Write(append(msg, '\n'))
+	return err
+}
+
+func (c *line) Recv() ([]byte, error) {
+	for {
+		msg, err := readLine(c.r, c.maxSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(bytes.TrimSpace(msg)) > 0 {
+				return msg, nil // unterminated last message
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(msg)) > 0 { // skip blank lines
+			return msg, nil
+		}
+	}
+}
+
+func (c *line) Close() error {
+	return c.wc.Close()
+}
+
+type header struct {
+	r       *bufio.Reader
+	wc      io.WriteCloser
+	maxSize int
+}
+
+// maxHeaderLineSize is the maximum size of a header line
+const maxHeaderLineSize = 1024
+
+func (c *header) Send(msg []byte) error {
+	_, err := fmt.Fprintf(c.wc, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	return err
+}
+
+func (c *header) Recv() ([]byte, error) {
+	size := -1
+	headers := 0
+	for {
+		l, err := readLine(c.r, maxHeaderLineSize)
+		if err != nil {
+			var tooLarge *MessageTooLargeError
+			if errors.As(err, &tooLarge) {
+				return nil, errors.New("header line too long")
+			}
+			return nil, err
+		}
+		l = bytes.TrimRight(l, "\r")
+		if len(l) == 0 {
+			if headers == 0 {
+				continue // skip blank lines between messages
+			}
+			break
+		}
+		headers++
+		name, value, found := strings.Cut(string(l), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid header line: '%s'", l)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if size, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || size < 0 {
+				return nil, fmt.Errorf("invalid content-length: '%s'", strings.TrimSpace(value))
+			}
+		}
+	}
+	if size < 0 {
+		return nil, errors.New("missing content-length header")
+	}
+	if size > c.maxSize {
+		return nil, &MessageTooLargeError{MaxSize: c.maxSize}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *header) Close() error {
+	return c.wc.Close()
+}
+
+// readLine reads a line (without its trailing newline) of up to maxSize bytes
+func readLine(r *bufio.Reader, maxSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if buf.Len()+len(chunk) > maxSize+1 { // includes the newline
+			return nil, &MessageTooLargeError{MaxSize: maxSize}
+		}
+		buf.Write(chunk)
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err != nil:
+			return buf.Bytes(), err
+		default:
+			return buf.Bytes()[:buf.Len()-1], nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package channel_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/channel"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestLineFraming(t *testing.T) {
+
+	t.Run("send and receive", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+		ch := channel.New(strings.NewReader("{\"a\":1}\n\n{\"b\":2}"), nopWriteCloser{out}, channel.LineFraming, 0)
+
+		// when
+		first, err1 := ch.Recv()
+		second, err2 := ch.Recv()
+		_, err3 := ch.Recv()
+		err4 := ch.Send([]byte(`{"c":3}`))
+
+		// then
+		require.NoError(t, err1)
+		assert.Equal(t, `{"a":1}`, string(first))
+		require.NoError(t, err2)
+		assert.Equal(t, `{"b":2}`, string(second)) // unterminated last message
+		assert.ErrorIs(t, err3, io.EOF)
+		require.NoError(t, err4)
+		assert.Equal(t, "{\"c\":3}\n", out.String())
+	})
+
+	t.Run("message too large", func(t *testing.T) {
+		// given
+		ch := channel.New(strings.NewReader(strings.Repeat("a", 32)+"\n"), nopWriteCloser{io.Discard}, channel.LineFraming, 16)
+
+		// when
+		_, err := ch.Recv()
+
+		// then
+		var tooLarge *channel.MessageTooLargeError
+		require.ErrorAs(t, err, &tooLarge)
+		assert.Equal(t, 16, tooLarge.MaxSize)
+	})
+
+	t.Run("newline in sent message", func(t *testing.T) {
+		// given
+		ch := channel.New(strings.NewReader(""), nopWriteCloser{io.Discard}, channel.LineFraming, 0)
+
+		// when
+		err := ch.Send([]byte("{\n}"))
+
+		// then
+		require.EqualError(t, err, "message contains a newline")
+	})
+}
+
+func TestHeaderFraming(t *testing.T) {
+
+	t.Run("send and receive", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+		ch := channel.New(strings.NewReader("Content-Length: 7\r\nContent-Type: application/json\r\n\r\n{\"a\":1}\r\ncontent-length: 7\r\n\r\n{\"b\":2}"),
+			nopWriteCloser{out}, channel.HeaderFraming, 0)
+
+		// when
+		first, err1 := ch.Recv()
+		second, err2 := ch.Recv()
+		_, err3 := ch.Recv()
+		err4 := ch.Send([]byte(`{"c":3}`))
+
+		// then
+		require.NoError(t, err1)
+		assert.Equal(t, `{"a":1}`, string(first))
+		require.NoError(t, err2)
+		assert.Equal(t, `{"b":2}`, string(second))
+		assert.ErrorIs(t, err3, io.EOF)
+		require.NoError(t, err4)
+		assert.Equal(t, "Content-Length: 7\r\n\r\n{\"c\":3}", out.String())
+	})
+
+	testCases := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			name:          "message too large",
+			input:         "Content-Length: 17\r\n\r\n",
+			expectedError: "message exceeds the maximum size of 16 bytes",
+		},
+		{
+			name:          "missing content-length",
+			input:         "Content-Type: application/json\r\n\r\n{}",
+			expectedError: "missing content-length header",
+		},
+		{
+			name:          "invalid content-length",
+			input:         "Content-Length: -1\r\n\r\n{}",
+			expectedError: "invalid content-length: '-1'",
+		},
+		{
+			name:          "header line too long",
+			input:         "X-Custom: " + strings.Repeat("a", 2048) + "\r\n\r\n",
+			expectedError: "header line too long",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			ch := channel.New(strings.NewReader(testCase.input), nopWriteCloser{io.Discard}, channel.HeaderFraming, 16)
+
+			// when
+			_, err := ch.Recv()
+
+			// then
+			require.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Package config loads the declarative definition of a server (prompts, static resources and tools backed by commands
+// or HTTP calls to local services) from a YAML or JSON file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative definition of a server
+type Config struct {
+	Name      string           `yaml:"name"`
+	Version   string           `yaml:"version"`
+	Prompts   []PromptConfig   `yaml:"prompts"`
+	Resources []ResourceConfig `yaml:"resources"`
+	Tools     []ToolConfig     `yaml:"tools"`
+	// dir is the directory in which the relative paths are resolved
+	dir string
+}
+
+// PromptConfig declares a prompt whose messages are rendered with the `text/template` package
+type PromptConfig struct {
+	Name        string           `yaml:"name"`
+	Title       string           `yaml:"title"`
+	Description string           `yaml:"description"`
+	Arguments   []ArgumentConfig `yaml:"arguments"`
+	Messages    []MessageConfig  `yaml:"messages"`
+	line        int
+}
+
+// ArgumentConfig declares an argument of a prompt, or an input property of a tool
+type ArgumentConfig struct {
+	Name        string `yaml:"name"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	// Type is the JSON schema type of a tool property (`string` by default). It is ignored for prompt arguments.
+	Type     string `yaml:"type"`
+	Required bool   `yaml:"required"`
+}
+
+// MessageConfig declares a message of a prompt
+type MessageConfig struct {
+	// Role is either `user` (by default) or `assistant`
+	Role string `yaml:"role"`
+	// Text is a template rendered with the arguments of the prompt (eg: `Summarize {{.topic}}`)
+	Text string `yaml:"text"`
+}
+
+// ResourceConfig declares a static resource, whose content is either inline or read from a file
+type ResourceConfig struct {
+	Name        string `yaml:"name"`
+	URI         string `yaml:"uri"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	// MimeType is detected from the extension of the file if it is not set
+	MimeType string `yaml:"mimeType"`
+	Text     string `yaml:"text"`
+	// File is the path of the file, relative to the directory of the config file. It is read on each request.
+	File string `yaml:"file"`
+	line int
+}
+
+// ToolConfig declares a tool backed by a command or by an HTTP call to a local service.
+// The arguments of the tool are available in the templates of the command, URL and body (eg: `{{.query}}`),
+// in which the declared arguments omitted by the client are empty strings.
+type ToolConfig struct {
+	Name        string           `yaml:"name"`
+	Title       string           `yaml:"title"`
+	Description string           `yaml:"description"`
+	Arguments   []ArgumentConfig `yaml:"arguments"`
+	// Command is the command to run (without a shell), whose standard output is the result of the tool
+	Command []string `yaml:"command"`
+	// HTTP is the request to send, whose response body is the result of the tool
+	HTTP *HTTPConfig `yaml:"http"`
+	line int
+}
+
+// HTTPConfig declares an HTTP request to a local service
+type HTTPConfig struct {
+	// Method is `GET` by default
+	Method string `yaml:"method"`
+	// URL is a template, in which the arguments are query-escaped. Its host must be a loopback address or `localhost`.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Body is a template, in which the `json` func encodes a value in JSON (eg: `{"query": {{json .query}}}`)
+	Body string `yaml:"body"`
+}
+
+// LoadFile loads the config from the given YAML or JSON file (see `Parse`)
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // the path of the config file is provided by the server owner
+	if err != nil {
+		return Config{}, fmt.Errorf("error while reading the config file: %w", err)
+	}
+	c, err := Parse(data, filepath.Dir(path))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses and validates the given YAML or JSON config, in which the relative paths are resolved from
+// the given directory. Unknown fields are rejected, and the errors contain the line of the invalid entries.
+func Parse(data []byte, dir string) (Config, error) {
+	c := Config{}
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+	if err := d.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+	c.dir = dir
+	// find the lines of the entries, to report them in the errors
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return Config{}, err
+	}
+	for i, line := range entryLines(root, "prompts") {
+		c.Prompts[i].line = line
+	}
+	for i, line := range entryLines(root, "resources") {
+		c.Resources[i].line = line
+	}
+	for i, line := range entryLines(root, "tools") {
+		c.Tools[i].line = line
+	}
+	if err := c.validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// entryLines returns the lines of the entries of the sequence with the given key in the given document
+func entryLines(root *yaml.Node, key string) []int {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	m := root.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			lines := make([]int, 0, len(m.Content[i+1].Content))
+			for _, entry := range m.Content[i+1].Content {
+				lines = append(lines, entry.Line)
+			}
+			return lines
+		}
+	}
+	return nil
+}
+
+// toolNameRegexp matches the valid tool names (see `server.RouterBuilder.Validate`)
+var toolNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+func (c Config) validate() error {
+	errs := []error{}
+	if c.Name == "" {
+		errs = append(errs, errors.New("missing server name"))
+	}
+	prompts := map[string]int{} // line of the first declaration of each name
+	for _, p := range c.Prompts {
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("line %d: prompt: missing name", p.line))
+		} else if line, found := prompts[p.Name]; found {
+			errs = append(errs, fmt.Errorf("line %d: prompt '%s': duplicate name (first declared at line %d)", p.line, p.Name, line))
+		} else {
+			prompts[p.Name] = p.line
+		}
+		if len(p.Messages) == 0 {
+			errs = append(errs, fmt.Errorf("line %d: prompt '%s': missing messages", p.line, p.Name))
+		}
+		for i, m := range p.Messages {
+			if m.Role != "" && m.Role != "user" && m.Role != "assistant" {
+				errs = append(errs, fmt.Errorf("line %d: prompt '%s': message #%d: invalid role '%s' (expected 'user' or 'assistant')", p.line, p.Name, i+1, m.Role))
+			}
+			if _, err := template.New("").Parse(m.Text); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: prompt '%s': message #%d: invalid template: %w", p.line, p.Name, i+1, err))
+			}
+		}
+		for i, a := range p.Arguments {
+			if a.Name == "" {
+				errs = append(errs, fmt.Errorf("line %d: prompt '%s': argument #%d: missing name", p.line, p.Name, i+1))
+			}
+		}
+	}
+	resources := map[string]int{}
+	for _, r := range c.Resources {
+		if r.URI == "" {
+			errs = append(errs, fmt.Errorf("line %d: resource '%s': missing URI", r.line, r.Name))
+		} else if line, found := resources[r.URI]; found {
+			errs = append(errs, fmt.Errorf("line %d: resource '%s': duplicate URI (first declared at line %d)", r.line, r.URI, line))
+		} else {
+			resources[r.URI] = r.line
+		}
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("line %d: resource '%s': missing name", r.line, r.URI))
+		}
+		if (r.Text == "") == (r.File == "") {
+			errs = append(errs, fmt.Errorf("line %d: resource '%s': either 'text' or 'file' must be set", r.line, r.URI))
+		}
+	}
+	tools := map[string]int{}
+	for _, t := range c.Tools {
+		if !toolNameRegexp.MatchString(t.Name) {
+			errs = append(errs, fmt.Errorf("line %d: tool '%s': invalid name (expected 1 to 128 letters, digits, '_', '-' or '.')", t.line, t.Name))
+		} else if line, found := tools[t.Name]; found {
+			errs = append(errs, fmt.Errorf("line %d: tool '%s': duplicate name (first declared at line %d)", t.line, t.Name, line))
+		} else {
+			tools[t.Name] = t.line
+		}
+		for i, a := range t.Arguments {
+			if a.Name == "" {
+				errs = append(errs, fmt.Errorf("line %d: tool '%s': argument #%d: missing name", t.line, t.Name, i+1))
+			}
+		}
+		switch {
+		case (len(t.Command) == 0) == (t.HTTP == nil):
+			errs = append(errs, fmt.Errorf("line %d: tool '%s': either 'command' or 'http' must be set", t.line, t.Name))
+		case t.HTTP != nil:
+			if err := t.HTTP.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: tool '%s': %w", t.line, t.Name, err))
+			}
+		default:
+			for i, arg := range t.Command {
+				if _, err := template.New("").Parse(arg); err != nil {
+					errs = append(errs, fmt.Errorf("line %d: tool '%s': command argument #%d: invalid template: %w", t.line, t.Name, i+1, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c HTTPConfig) validate() error {
+	if _, err := template.New("").Funcs(bodyFuncs).Parse(c.Body); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	if _, err := template.New("").Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid URL template: %w", err)
+	}
+	// the host is checked before rendering the template, so that it cannot be changed by the arguments
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid URL '%s'", c.URL)
+	}
+	if !isLocalHost(u.Hostname()) {
+		return fmt.Errorf("URL '%s' is not local", c.URL)
+	}
+	return nil
+}
+
+func isLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
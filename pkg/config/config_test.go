@@ -0,0 +1,239 @@
+package config_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/config"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + string(body)))
+	}))
+	defer svc.Close()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guide.md"), []byte("# Guide"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.yaml"), []byte(`
+name: docs
+version: "1.0"
+prompts:
+  - name: summarize
+    description: Summarize a topic
+    arguments:
+      - name: topic
+        required: true
+    messages:
+      - text: Summarize {{.topic}}
+      - role: assistant
+        text: Here is a summary of {{.topic}}
+resources:
+  - name: guide
+    uri: file:///guide.md
+    file: guide.md
+  - name: motd
+    uri: text:///motd
+    text: Hello
+tools:
+  - name: echo
+    arguments:
+      - name: message
+        required: true
+    command: [echo, "{{.message}}"]
+  - name: greet
+    arguments:
+      - name: name
+    command: [echo, "hello", "{{.name}}"]
+  - name: search
+    arguments:
+      - name: query
+        required: true
+      - name: lang
+    http:
+      method: POST
+      url: `+svc.URL+`/search?q={{.query}}&lang={{.lang}}
+      body: '{"query": {{json .query}}, "lang": {{json .lang}}}'
+`), 0o600))
+
+	// when
+	c, err := config.LoadFile(filepath.Join(dir, "server.yaml"))
+
+	// then
+	require.NoError(t, err)
+	cl := mcptest.NewClient(t, c.NewRouterBuilder(logger).Build())
+	assert.Equal(t, "docs", cl.InitializeResult.ServerInfo.Name)
+
+	t.Run("prompt", func(t *testing.T) {
+		// when
+		result, err := cl.GetPrompt(context.Background(), "summarize", map[string]string{"topic": "MCP"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []api.PromptMessage{
+			{Role: api.RoleUser, Content: api.Text("Summarize MCP")},
+			{Role: api.RoleAssistant, Content: api.Text("Here is a summary of MCP")},
+		}, result.Messages)
+	})
+
+	t.Run("file resource", func(t *testing.T) {
+		// when
+		result, err := cl.ReadResource(context.Background(), "file:///guide.md")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result.Contents, 1)
+		assert.Equal(t, "# Guide", result.Contents[0].Text)
+		assert.Equal(t, "text/markdown; charset=utf-8", *result.Contents[0].MimeType)
+	})
+
+	t.Run("inline resource", func(t *testing.T) {
+		// when
+		result, err := cl.ReadResource(context.Background(), "text:///motd")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result.Contents, 1)
+		assert.Equal(t, "Hello", result.Contents[0].Text)
+	})
+
+	t.Run("command tool", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "echo", map[string]any{"message": "hello; rm -rf /"})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "hello; rm -rf /\n")
+	})
+
+	t.Run("command tool without optional argument", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "greet", map[string]any{})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "hello \n")
+	})
+
+	t.Run("http tool", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "search", map[string]any{"query": "a&b", "lang": "en"})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, `POST /search?q=a%26b&lang=en {"query": "a&b", "lang": "en"}`)
+	})
+
+	t.Run("http tool without optional argument", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "search", map[string]any{"query": "a&b"})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, `POST /search?q=a%26b&lang= {"query": "a&b", "lang": ""}`)
+	})
+}
+
+func TestParse(t *testing.T) {
+
+	t.Run("json", func(t *testing.T) {
+		// when
+		c, err := config.Parse([]byte(`{"name": "docs", "resources": [{"name": "motd", "uri": "text:///motd", "text": "Hello"}]}`), ".")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "docs", c.Name)
+		require.Len(t, c.Resources, 1)
+		assert.Equal(t, "Hello", c.Resources[0].Text)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		// when
+		_, err := config.Parse([]byte(`
+name: docs
+prompts:
+  - name: summarize
+    mesages: []
+`), ".")
+
+		// then
+		require.EqualError(t, err, "yaml: unmarshal errors:\n  line 5: field mesages not found in type config.PromptConfig")
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		// when
+		_, err := config.Parse([]byte(`
+name: docs
+prompts:
+  - name: summarize
+    messages:
+      - role: system
+        text: "{{.topic"
+resources:
+  - name: motd
+    uri: text:///motd
+tools:
+  - name: search
+    command: [grep]
+    http:
+      url: http://localhost:8080/search
+  - name: fetch
+    http:
+      url: https://example.com/{{.path}}
+`), ".")
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, `line 4: prompt 'summarize': message #1: invalid role 'system' (expected 'user' or 'assistant')
+line 4: prompt 'summarize': message #1: invalid template: template: :1: unclosed action
+line 9: resource 'text:///motd': either 'text' or 'file' must be set
+line 12: tool 'search': either 'command' or 'http' must be set
+line 16: tool 'fetch': URL 'https://example.com/{{.path}}' is not local`, err.Error())
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		// when
+		_, err := config.Parse([]byte(`
+name: docs
+prompts:
+  - name: summarize
+    messages:
+      - text: Summarize
+  - name: summarize
+    messages:
+      - text: Summarize again
+resources:
+  - name: motd
+    uri: text:///motd
+    text: Hello
+  - name: other
+    uri: text:///motd
+    text: Hi
+tools:
+  - name: echo
+    command: [echo]
+  - name: echo
+    command: [echo, again]
+`), ".")
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, `line 7: prompt 'summarize': duplicate name (first declared at line 4)
+line 14: resource 'text:///motd': duplicate URI (first declared at line 11)
+line 20: tool 'echo': duplicate name (first declared at line 18)`, err.Error())
+	})
+}
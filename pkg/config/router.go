@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+)
+
+// maxToolOutputSize is the maximum size of the output of a command or of an HTTP response returned by a tool
+const maxToolOutputSize = 1 << 20
+
+// NewRouterBuilder returns a builder with the prompts, resources and tools of the config
+func (c Config) NewRouterBuilder(logger *slog.Logger) *server.RouterBuilder {
+	return c.Register(server.NewRouterBuilder(c.Name, c.Version, logger))
+}
+
+// Register adds the prompts, resources and tools of the config to the given builder
+func (c Config) Register(b *server.RouterBuilder) *server.RouterBuilder {
+	for _, p := range c.Prompts {
+		b.WithPromptTemplate(p.prompt(), p.messages())
+	}
+	for _, r := range c.Resources {
+		b.WithResource(r.resource(), r.handle(c.dir))
+	}
+	for _, t := range c.Tools {
+		b.WithTool(t.tool(), t.handle(c.dir))
+	}
+	return b
+}
+
+func (p PromptConfig) prompt() api.Prompt {
+	prompt := api.NewPrompt(p.Name)
+	if p.Title != "" {
+		prompt = prompt.WithTitle(p.Title)
+	}
+	if p.Description != "" {
+		prompt = prompt.WithDescription(p.Description)
+	}
+	for _, a := range p.Arguments {
+		prompt = prompt.WithArgument(a.Name, a.Title, a.Description, a.Required)
+	}
+	return prompt
+}
+
+func (p PromptConfig) messages() []server.PromptMessageTemplate {
+	messages := make([]server.PromptMessageTemplate, 0, len(p.Messages))
+	for _, m := range p.Messages {
+		if m.Role == string(api.RoleAssistant) {
+			messages = append(messages, server.AssistantMessage(m.Text))
+		} else {
+			messages = append(messages, server.UserMessage(m.Text))
+		}
+	}
+	return messages
+}
+
+func (r ResourceConfig) resource() api.Resource {
+	resource := api.NewResource(r.Name, r.URI)
+	if r.Title != "" {
+		resource = resource.WithTitle(r.Title)
+	}
+	if r.Description != "" {
+		resource = resource.WithDescription(r.Description)
+	}
+	if mimeType := r.mimeType(); mimeType != "" {
+		resource = resource.WithMimeType(mimeType)
+	}
+	return resource
+}
+
+func (r ResourceConfig) mimeType() string {
+	if r.MimeType != "" || r.File == "" {
+		return r.MimeType
+	}
+	return mime.TypeByExtension(filepath.Ext(r.File))
+}
+
+func (r ResourceConfig) handle(dir string) server.ResourceHandleFunc {
+	mimeType := api.StringPtr(r.mimeType())
+	if *mimeType == "" {
+		mimeType = nil
+	}
+	return func(_ context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+		contents := api.ReadResourceResultContentsElem{
+			Uri:      params.Uri,
+			MimeType: mimeType,
+			Text:     r.Text,
+		}
+		if r.File != "" {
+			data, err := os.ReadFile(resolve(dir, r.File)) //nolint:gosec // the path is declared in the config file
+			if err != nil {
+				return api.ReadResourceResult{}, fmt.Errorf("error while reading resource '%s': %w", params.Uri, err)
+			}
+			if utf8.Valid(data) {
+				contents.Text = string(data)
+			} else {
+				contents.Blob = api.EncodeBase64(data)
+			}
+		}
+		return api.ReadResourceResult{
+			Contents: []api.ReadResourceResultContentsElem{contents},
+		}, nil
+	}
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (t ToolConfig) tool() api.Tool {
+	tool := api.NewTool(t.Name)
+	if t.Title != "" {
+		tool = tool.WithTitle(t.Title)
+	}
+	if t.Description != "" {
+		tool = tool.WithDescription(t.Description)
+	}
+	for _, a := range t.Arguments {
+		propType := a.Type
+		if propType == "" {
+			propType = api.TypeString
+		}
+		tool = tool.WithInputProperty(a.Name, propType, a.Description, a.Required)
+	}
+	return tool
+}
+
+func (t ToolConfig) handle(dir string) server.ToolHandleFunc {
+	if t.HTTP != nil {
+		return t.HTTP.handle(t.Arguments)
+	}
+	args := make([]*template.Template, 0, len(t.Command))
+	for _, arg := range t.Command {
+		args = append(args, template.Must(template.New("").Parse(arg))) // validated when the config was parsed
+	}
+	return func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+		values := withDeclaredArguments(params.Arguments, t.Arguments)
+		command := make([]string, 0, len(args))
+		for _, arg := range args {
+			a, err := render(arg, values)
+			if err != nil {
+				return api.CallToolResult{}, err
+			}
+			command = append(command, a)
+		}
+		stdout := &limitedBuffer{limit: maxToolOutputSize}
+		stderr := &limitedBuffer{limit: maxToolOutputSize}
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...) //nolint:gosec // the command is declared in the config file, and run without a shell
+		cmd.Dir = dir
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return api.CallToolResult{}, fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return api.CallToolResult{
+			Content: []api.CallToolResultContentElem{api.Text(stdout.String())},
+		}, nil
+	}
+}
+
+var bodyFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b := &bytes.Buffer{}
+		e := json.NewEncoder(b)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(v); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	},
+}
+
+func (c HTTPConfig) handle(arguments []ArgumentConfig) server.ToolHandleFunc {
+	// the arguments are query-escaped in the URL
+	u := template.Must(template.New("").Parse(c.URL))
+	body := template.Must(template.New("").Funcs(bodyFuncs).Parse(c.Body))
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+		values := withDeclaredArguments(params.Arguments, arguments)
+		escaped := make(map[string]any, len(values))
+		for k, v := range values {
+			escaped[k] = url.QueryEscape(fmt.Sprint(v))
+		}
+		target, err := render(u, escaped)
+		if err != nil {
+			return api.CallToolResult{}, err
+		}
+		b, err := render(body, values)
+		if err != nil {
+			return api.CallToolResult{}, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(b))
+		if err != nil {
+			return api.CallToolResult{}, fmt.Errorf("invalid request: %w", err)
+		}
+		for k, v := range c.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return api.CallToolResult{}, fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxToolOutputSize))
+		if err != nil {
+			return api.CallToolResult{}, fmt.Errorf("error while reading the response: %w", err)
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			return api.CallToolResult{}, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return api.CallToolResult{
+			Content: []api.CallToolResultContentElem{api.Text(string(data))},
+		}, nil
+	}
+}
+
+// withDeclaredArguments returns the given arguments, along with an empty string for each declared argument which is
+// missing, since the templates would render `<no value>` otherwise
+func withDeclaredArguments(args map[string]any, declared []ArgumentConfig) map[string]any {
+	values := make(map[string]any, len(declared)+len(args))
+	for _, a := range declared {
+		values[a.Name] = ""
+	}
+	maps.Copy(values, args)
+	return values
+}
+
+func render(t *template.Template, args map[string]any) (string, error) {
+	b := &strings.Builder{}
+	if err := t.Execute(b, args); err != nil {
+		return "", fmt.Errorf("error while rendering the template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// limitedBuffer is a buffer which silently discards the data written beyond its limit
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining < len(p) {
+		_, _ = b.Buffer.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/server"
+)
+
+// DefaultWatchInterval is the default interval at which the files are checked by `Watcher.Watch`
+const DefaultWatchInterval = time.Second
+
+// Watcher reloads the config when the config file or the files of its resources change, so that the prompts,
+// resources and tools can be edited without restarting the server
+type Watcher struct {
+	path   string
+	logger *slog.Logger
+	router *server.ReloadableRouter
+	mu     sync.Mutex
+	config Config
+	// the state of the files when the config was last loaded
+	files map[string]fileState
+	// the state of the files when the config last failed to load, to report the errors only once
+	failed map[string]fileState
+}
+
+// fileState is the zero value for the files which do not exist
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher loads the config file at the given path, and returns a watcher which serves its prompts, resources
+// and tools with a `server.ReloadableRouter`
+func NewWatcher(path string, logger *slog.Logger) (*Watcher, error) {
+	c, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	router, err := server.NewReloadableRouter(c.NewRouterBuilder(logger), logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		path:   path,
+		logger: logger,
+		router: router,
+		config: c,
+		files:  stat(path, c),
+	}, nil
+}
+
+// Router returns the router to serve
+func (w *Watcher) Router() server.Router {
+	return w.router.Router()
+}
+
+// Watch checks the files at the given interval until the context is done
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Check(); err != nil {
+				w.logger.Error("failed to reload the config", "path", w.path, "error", err)
+			}
+		}
+	}
+}
+
+// Check reloads the config if the config file or the files of its resources changed since it was last loaded.
+// If the new config is invalid, the previous one is still served and an error is returned (only once per change).
+func (w *Watcher) Check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := stat(w.path, w.config)
+	if maps.Equal(files, w.files) || maps.Equal(files, w.failed) {
+		return nil
+	}
+	w.logger.Info("reloading the config", "path", w.path)
+	c, err := LoadFile(w.path)
+	if err != nil {
+		w.failed = files
+		return err
+	}
+	files = stat(w.path, c)
+	if err := w.router.Reload(c.NewRouterBuilder(w.logger), w.updated(c, files)...); err != nil {
+		w.failed = files
+		return err
+	}
+	w.config, w.files, w.failed = c, files, nil
+	return nil
+}
+
+// updated returns the URIs of the resources of the given config whose contents changed since the config was last loaded
+func (w *Watcher) updated(c Config, files map[string]fileState) []string {
+	previous := make(map[string]ResourceConfig, len(w.config.Resources))
+	for _, r := range w.config.Resources {
+		previous[r.URI] = r
+	}
+	updated := []string{}
+	for _, r := range c.Resources {
+		p, found := previous[r.URI]
+		switch {
+		case !found:
+			continue
+		case p.Text != r.Text || p.File != r.File:
+			updated = append(updated, r.URI)
+		case r.File != "":
+			if path := resolve(c.dir, r.File); files[path] != w.files[path] {
+				updated = append(updated, r.URI)
+			}
+		}
+	}
+	return updated
+}
+
+// stat returns the state of the config file and of the files of its resources
+func stat(path string, c Config) map[string]fileState {
+	files := map[string]fileState{
+		path: statFile(path),
+	}
+	for _, r := range c.Resources {
+		if r.File != "" {
+			p := resolve(c.dir, r.File)
+			files[p] = statFile(p)
+		}
+	}
+	return files
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
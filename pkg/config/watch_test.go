@@ -0,0 +1,137 @@
+package config_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/config"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.yaml")
+	// write sets the modification time explicitly, since successive writes may happen within the resolution of the clock
+	modTime := time.Now()
+	write := func(t *testing.T, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), modTime, modTime))
+	}
+	write(t, "guide.md", "# Guide")
+	write(t, "server.yaml", `
+name: docs
+version: "1.0"
+prompts:
+  - name: summarize
+    messages:
+      - text: Summarize
+resources:
+  - name: guide
+    uri: file:///guide.md
+    file: guide.md
+`)
+	w, err := config.NewWatcher(path, logger)
+	require.NoError(t, err)
+	cl := mcptest.NewClient(t, w.Router())
+	require.NoError(t, cl.Subscribe(context.Background(), "file:///guide.md"))
+
+	t.Run("no change", func(t *testing.T) {
+		// when
+		err := w.Check()
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, cl.Notifications())
+	})
+
+	t.Run("edit the resource file", func(t *testing.T) {
+		// given
+		write(t, "guide.md", "# Updated guide")
+
+		// when
+		err := w.Check()
+
+		// then
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		n, err := cl.WaitForNotification(ctx, "notifications/resources/updated")
+		require.NoError(t, err)
+		params := api.ResourceUpdatedNotificationParams{}
+		require.NoError(t, n.UnmarshalParams(&params))
+		assert.Equal(t, "file:///guide.md", params.Uri)
+		result, err := cl.ReadResource(context.Background(), "file:///guide.md")
+		require.NoError(t, err)
+		assert.Equal(t, "# Updated guide", result.Contents[0].Text)
+	})
+
+	t.Run("rollback on invalid edit", func(t *testing.T) {
+		// given
+		write(t, "server.yaml", `
+name: docs
+version: "1.0"
+prompts:
+  - name: summarize
+    messages:
+      - text: Summarize {{.topic
+`)
+
+		// when
+		err := w.Check()
+
+		// then
+		require.ErrorContains(t, err, "line 5: prompt 'summarize': message #1: invalid template")
+		prompts, err := cl.ListPrompts(context.Background())
+		require.NoError(t, err)
+		require.Len(t, prompts.Prompts, 1)
+		_, err = cl.ReadResource(context.Background(), "file:///guide.md")
+		require.NoError(t, err)
+		// the error is only reported once
+		require.NoError(t, w.Check())
+	})
+
+	t.Run("add a prompt", func(t *testing.T) {
+		// given
+		write(t, "server.yaml", `
+name: docs
+version: "1.0"
+prompts:
+  - name: summarize
+    messages:
+      - text: Summarize
+  - name: translate
+    messages:
+      - text: Translate
+resources:
+  - name: guide
+    uri: file:///guide.md
+    file: guide.md
+`)
+
+		// when
+		err := w.Check()
+
+		// then
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = cl.WaitForNotification(ctx, "notifications/prompts/list_changed")
+		require.NoError(t, err)
+		prompts, err := cl.ListPrompts(context.Background())
+		require.NoError(t, err)
+		require.Len(t, prompts.Prompts, 2)
+		assert.Equal(t, "translate", prompts.Prompts[1].Name)
+	})
+}
@@ -0,0 +1,83 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertToolResult asserts that the actual result is equal to the expected one, once both are encoded in JSON,
+// so that content blocks can be given as structs (eg: `api.TextContent`) or maps
+func AssertToolResult(t testing.TB, expected, actual api.CallToolResult) bool {
+	t.Helper()
+	return assertJSONEq(t, expected, actual)
+}
+
+// AssertToolError asserts that the result of a tool call is flagged as an error
+func AssertToolError(t testing.TB, actual api.CallToolResult) bool {
+	t.Helper()
+	return assert.True(t, actual.IsError != nil && *actual.IsError, "expected the tool result to be an error")
+}
+
+// AssertContent asserts that the content blocks of the actual result are equal to the expected ones,
+// once they are encoded in JSON
+func AssertContent(t testing.TB, expected []any, actual api.CallToolResult) bool {
+	t.Helper()
+	return assertJSONEq(t, expected, actual.Content)
+}
+
+// AssertTextContent asserts that the content of the actual result only contains text blocks with the expected texts
+func AssertTextContent(t testing.TB, actual api.CallToolResult, expected ...string) bool {
+	t.Helper()
+	blocks := make([]any, 0, len(expected))
+	for _, text := range expected {
+		blocks = append(blocks, api.Text(text))
+	}
+	return AssertContent(t, blocks, actual)
+}
+
+// AssertStructuredContent asserts that the structured content of the actual result is equal to the expected value,
+// once both are encoded in JSON
+func AssertStructuredContent(t testing.TB, expected any, actual api.CallToolResult) bool {
+	t.Helper()
+	return assertJSONEq(t, expected, actual.StructuredContent)
+}
+
+// AssertNotification asserts that the next notification with the given method received by the client (see
+// `WaitForNotification`) has the given params, and is received within the given timeout.
+// The params are compared once encoded in JSON, and are ignored if nil.
+func AssertNotification(t testing.TB, c *Client, timeout time.Duration, method string, expectedParams any) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	n, err := c.WaitForNotification(ctx, method)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	if expectedParams == nil {
+		return true
+	}
+	expected, err := json.Marshal(expectedParams)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	return assert.JSONEq(t, string(expected), string(n.Params))
+}
+
+func assertJSONEq(t testing.TB, expected, actual any) bool {
+	t.Helper()
+	expectedJSON, err := json.Marshal(expected)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	actualJSON, err := json.Marshal(actual)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	return assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
@@ -0,0 +1,445 @@
+// Package mcptest provides an in-process client to unit-test the routers built with the `server` package.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/channel"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/handler"
+)
+
+type SamplingHandleFunc func(ctx context.Context, params api.CreateMessageRequestParams) (api.CreateMessageResult, error)
+
+type ElicitationHandleFunc func(ctx context.Context, params api.ElicitRequestParams) (api.ElicitResult, error)
+
+// Option configures the client returned by `NewClient`
+type Option func(*config)
+
+type config struct {
+	clientInfo           api.Implementation
+	protocolVersion      string
+	capabilities         api.ClientCapabilities
+	sampling             SamplingHandleFunc
+	elicitation          ElicitationHandleFunc
+	roots                []api.Root
+	concurrency          int
+	logger               *slog.Logger
+	skipOutputValidation bool
+}
+
+// WithClientInfo sets the name and version of the client sent in the `initialize` request
+func WithClientInfo(name, version string) Option {
+	return func(c *config) {
+		c.clientInfo = api.Implementation{
+			Name:    name,
+			Version: version,
+		}
+	}
+}
+
+// WithProtocolVersion sets the protocol version requested in the `initialize` request (the latest one by default)
+func WithProtocolVersion(version string) Option {
+	return func(c *config) {
+		c.protocolVersion = version
+	}
+}
+
+// WithSampling declares the sampling capability, and handles the `sampling/createMessage` requests with the given func,
+// whose context is cancelled when the server abandons the request
+func WithSampling(handle SamplingHandleFunc) Option {
+	return func(c *config) {
+		c.capabilities.Sampling = map[string]any{}
+		c.sampling = handle
+	}
+}
+
+// WithElicitation declares the elicitation capability, and handles the `elicitation/create` requests with the given func,
+// whose context is cancelled when the server abandons the request
+func WithElicitation(handle ElicitationHandleFunc) Option {
+	return func(c *config) {
+		c.capabilities.Elicitation = map[string]any{}
+		c.elicitation = handle
+	}
+}
+
+// WithRoots declares the roots capability, and returns the given roots in the response to the `roots/list` requests
+func WithRoots(roots ...api.Root) Option {
+	return func(c *config) {
+		c.capabilities.Roots = &api.ClientCapabilitiesRoots{}
+		c.roots = roots
+	}
+}
+
+// WithConcurrency sets the maximum number of requests that the server handles concurrently (1 by default)
+func WithConcurrency(concurrency int) Option {
+	return func(c *config) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithLogger sets the logger of the server (which discards all records by default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithoutOutputValidation disables the validation of the tool results against the output schema of the tools
+func WithoutOutputValidation() Option {
+	return func(c *config) {
+		c.skipOutputValidation = true
+	}
+}
+
+// Notification is a notification received from the server
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// UnmarshalParams decodes the parameters of the notification into v
+func (n Notification) UnmarshalParams(v any) error {
+	return json.Unmarshal(n.Params, v)
+}
+
+// Client is an initialized client connected to an in-process server
+type Client struct {
+	*jrpc2.Client
+	// InitializeResult is the response to the `initialize` request
+	InitializeResult     api.InitializeResult
+	t                    testing.TB
+	skipOutputValidation bool
+	mu                   sync.Mutex
+	notifications        []Notification
+	notified             chan struct{}
+	waited               map[string]int // index of the next notification to return by `WaitForNotification`, per method
+	callbacks            map[api.RequestId]context.CancelFunc
+	outputSchemas        map[string]*api.Schema // reset when the list of tools changes
+}
+
+// NewClient starts a server for the given router, and returns a client which is connected to it and initialized.
+// The client and the server are stopped when the test completes.
+func NewClient(t testing.TB, router server.Router, opts ...Option) *Client {
+	t.Helper()
+	cfg := &config{
+		clientInfo: api.Implementation{
+			Name:    "mcptest",
+			Version: "0.1",
+		},
+		protocolVersion: api.LatestProtocolVersion,
+		concurrency:     1,
+		logger:          slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	c := &Client{
+		t:                    t,
+		skipOutputValidation: cfg.skipOutputValidation,
+		notified:             make(chan struct{}),
+		waited:               map[string]int{},
+		callbacks:            map[api.RequestId]context.CancelFunc{},
+	}
+	c2s, s2c := channel.Direct()
+	sctx := server.NewSessionContext(context.Background())
+	srv := jrpc2.NewServer(handler.Map(router), &jrpc2.ServerOptions{
+		Logger:      server.SlogToLogBridge(cfg.logger),
+		AllowPush:   true,
+		Concurrency: cfg.concurrency,
+		NewContext: func() context.Context {
+			return sctx
+		},
+	}).Start(server.NewSessionChannel(sctx, s2c))
+	c.Client = jrpc2.NewClient(c2s, &jrpc2.ClientOptions{
+		OnNotify:   c.onNotify,
+		OnCallback: c.cancellable(cfg.onCallback()),
+		OnCancel:   onCancel,
+	})
+	t.Cleanup(func() {
+		_ = c.Close()
+		srv.Stop()
+	})
+
+	if err := c.CallResult(context.Background(), "initialize", initializeParams{
+		InitializeRequestParams: api.InitializeRequestParams{
+			ProtocolVersion: cfg.protocolVersion,
+			ClientInfo:      cfg.clientInfo,
+		},
+		Capabilities: cfg.capabilitiesParams(),
+	}, &c.InitializeResult); err != nil {
+		t.Fatalf("failed to initialize the client: %v", err)
+	}
+	if err := c.Notify(context.Background(), "notifications/initialized", nil); err != nil {
+		t.Fatalf("failed to notify the server: %v", err)
+	}
+	return c
+}
+
+// initializeParams are the params of the `initialize` request, whose capabilities are encoded explicitly, since the
+// empty `sampling` and `elicitation` capabilities of `api.ClientCapabilities` are omitted from its JSON encoding
+type initializeParams struct {
+	api.InitializeRequestParams
+	Capabilities map[string]any `json:"capabilities"`
+}
+
+func (cfg *config) capabilitiesParams() map[string]any {
+	capabilities := map[string]any{}
+	if cfg.capabilities.Sampling != nil {
+		capabilities["sampling"] = cfg.capabilities.Sampling
+	}
+	if cfg.capabilities.Elicitation != nil {
+		capabilities["elicitation"] = cfg.capabilities.Elicitation
+	}
+	if cfg.capabilities.Roots != nil {
+		capabilities["roots"] = cfg.capabilities.Roots
+	}
+	return capabilities
+}
+
+// onCallback handles the requests sent by the server, according to the simulated client capabilities
+func (cfg *config) onCallback() jrpc2.Handler {
+	callbacks := handler.Map{}
+	if cfg.sampling != nil {
+		callbacks["sampling/createMessage"] = func(ctx context.Context, req *jrpc2.Request) (any, error) {
+			params := api.CreateMessageRequestParams{}
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, jrpc2.Errorf(jrpc2.InvalidParams, "invalid params: %v", err)
+			}
+			return cfg.sampling(ctx, params)
+		}
+	}
+	if cfg.elicitation != nil {
+		callbacks["elicitation/create"] = func(ctx context.Context, req *jrpc2.Request) (any, error) {
+			params := api.ElicitRequestParams{}
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, jrpc2.Errorf(jrpc2.InvalidParams, "invalid params: %v", err)
+			}
+			return cfg.elicitation(ctx, params)
+		}
+	}
+	if cfg.roots != nil {
+		callbacks["roots/list"] = func(_ context.Context, _ *jrpc2.Request) (any, error) {
+			return api.ListRootsResult{
+				Roots: cfg.roots,
+			}, nil
+		}
+	}
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		if h, ok := callbacks[req.Method()]; ok {
+			return h(ctx, req)
+		}
+		return nil, jrpc2.Errorf(jrpc2.MethodNotFound, "method '%s' is not supported by the client", req.Method())
+	}
+}
+
+// onCancel notifies the server when the context of a request ends before the response is received
+func onCancel(cl *jrpc2.Client, rsp *jrpc2.Response) {
+	id := api.RequestId{}
+	if err := json.Unmarshal([]byte(rsp.ID()), &id); err != nil {
+		return
+	}
+	_ = cl.Notify(context.Background(), "notifications/cancelled", api.CancelledNotificationParams{
+		RequestId: id,
+	})
+}
+
+// cancellable cancels the context of the requests sent by the server when it notifies the client that they were
+// abandoned with a `notifications/cancelled` notification
+func (c *Client) cancellable(h jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		id := api.RequestId{}
+		if err := json.Unmarshal([]byte(req.ID()), &id); err != nil {
+			return h(ctx, req)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		c.mu.Lock()
+		c.callbacks[id] = cancel
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.callbacks, id)
+			c.mu.Unlock()
+		}()
+		return h(ctx, req)
+	}
+}
+
+func (c *Client) onNotify(req *jrpc2.Request) {
+	n := Notification{
+		Method: req.Method(),
+	}
+	if req.HasParams() {
+		n.Params = json.RawMessage(req.ParamString())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n.Method == "notifications/cancelled" {
+		params := api.CancelledNotificationParams{}
+		if err := n.UnmarshalParams(&params); err == nil {
+			if cancel, found := c.callbacks[params.RequestId]; found {
+				cancel()
+			}
+		}
+	}
+	c.notifications = append(c.notifications, n)
+	if n.Method == "notifications/tools/list_changed" {
+		c.outputSchemas = nil
+	}
+	close(c.notified)
+	c.notified = make(chan struct{})
+}
+
+// Notifications returns the notifications received so far
+func (c *Client) Notifications() []Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Notification{}, c.notifications...)
+}
+
+// WaitForNotification returns the next notification received with the given method, waiting until one is received
+// or the context is done. Each notification is returned only once, so successive calls return successive notifications.
+func (c *Client) WaitForNotification(ctx context.Context, method string) (Notification, error) {
+	for {
+		c.mu.Lock()
+		for i := c.waited[method]; i < len(c.notifications); i++ {
+			if n := c.notifications[i]; n.Method == method {
+				c.waited[method] = i + 1
+				c.mu.Unlock()
+				return n, nil
+			}
+		}
+		c.waited[method] = len(c.notifications)
+		notified := c.notified
+		c.mu.Unlock()
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			return Notification{}, fmt.Errorf("no '%s' notification received: %w", method, ctx.Err())
+		}
+	}
+}
+
+func (c *Client) ListPrompts(ctx context.Context) (api.ListPromptsResult, error) {
+	result := api.ListPromptsResult{}
+	err := c.CallResult(ctx, "prompts/list", api.ListPromptsRequestParams{}, &result)
+	return result, err
+}
+
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (api.GetPromptResult, error) {
+	result := api.GetPromptResult{}
+	if err := c.CallResult(ctx, "prompts/get", api.GetPromptRequestParams{
+		Name:      name,
+		Arguments: args,
+	}, &result); err != nil {
+		return result, err
+	}
+	for i, m := range result.Messages {
+		content, err := api.DecodeContentBlock(m.Content)
+		if err != nil {
+			return result, fmt.Errorf("invalid content in message %d of prompt '%s': %w", i, name, err)
+		}
+		result.Messages[i].Content = content
+	}
+	return result, nil
+}
+
+func (c *Client) ListResources(ctx context.Context) (api.ListResourcesResult, error) {
+	result := api.ListResourcesResult{}
+	err := c.CallResult(ctx, "resources/list", api.ListResourcesRequestParams{}, &result)
+	return result, err
+}
+
+func (c *Client) ReadResource(ctx context.Context, uri string) (api.ReadResourceResult, error) {
+	result := api.ReadResourceResult{}
+	err := c.CallResult(ctx, "resources/read", api.ReadResourceRequestParams{
+		Uri: uri,
+	}, &result)
+	return result, err
+}
+
+// Subscribe subscribes to the updates of the resource with the given URI
+func (c *Client) Subscribe(ctx context.Context, uri string) error {
+	_, err := c.Call(ctx, "resources/subscribe", api.SubscribeRequestParams{
+		Uri: uri,
+	})
+	return err
+}
+
+// Unsubscribe unsubscribes from the updates of the resource with the given URI
+func (c *Client) Unsubscribe(ctx context.Context, uri string) error {
+	_, err := c.Call(ctx, "resources/unsubscribe", api.UnsubscribeRequestParams{
+		Uri: uri,
+	})
+	return err
+}
+
+func (c *Client) ListTools(ctx context.Context) (api.ListToolsResult, error) {
+	result := api.ListToolsResult{}
+	err := c.CallResult(ctx, "tools/list", api.ListToolsRequestParams{}, &result)
+	return result, err
+}
+
+// CallTool calls the tool with the given name and arguments, and decodes the content blocks of the result into
+// their concrete types (eg: `api.TextContent`).
+// Unless disabled with `WithoutOutputValidation`, the test fails if the result does not conform to the output schema
+// of the tool.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (api.CallToolResult, error) {
+	result := api.CallToolResult{}
+	if err := c.CallResult(ctx, "tools/call", api.CallToolRequestParams{
+		Name:      name,
+		Arguments: args,
+	}, &result); err != nil {
+		return result, err
+	}
+	content, err := api.DecodeContentBlocks(result.Content)
+	if err != nil {
+		return result, fmt.Errorf("invalid content in the result of tool '%s': %w", name, err)
+	}
+	result.Content = content
+	if !c.skipOutputValidation {
+		c.validateToolResult(ctx, name, result)
+	}
+	return result, nil
+}
+
+func (c *Client) validateToolResult(ctx context.Context, name string, result api.CallToolResult) {
+	c.t.Helper()
+	c.mu.Lock()
+	schemas := c.outputSchemas
+	c.mu.Unlock()
+	if schemas == nil {
+		tools, err := c.ListTools(ctx)
+		if err != nil {
+			c.t.Errorf("failed to list the tools to validate the result of tool '%s': %v", name, err)
+			return
+		}
+		schemas = map[string]*api.Schema{}
+		for _, tool := range tools.Tools {
+			if tool.OutputSchema == nil {
+				continue
+			}
+			if schemas[tool.Name], err = api.CompileSchema(tool.OutputSchema); err != nil {
+				c.t.Errorf("invalid output schema of tool '%s': %v", tool.Name, err)
+				return
+			}
+		}
+		c.mu.Lock()
+		c.outputSchemas = schemas
+		c.mu.Unlock()
+	}
+	if schema, ok := schemas[name]; ok {
+		if err := server.ValidateToolResult(schema, result); err != nil {
+			c.t.Errorf("invalid result of tool '%s': %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package mcptest_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("echo"), func(_ context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+			return api.CallToolResult{
+				Content: []api.CallToolResultContentElem{
+					api.Text(params.Arguments["message"].(string)),
+				},
+				StructuredContent: map[string]any{
+					"message": params.Arguments["message"],
+				},
+			}, nil
+		}).
+		WithTool(api.NewTool("notify"), func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			err := jrpc2.ServerFromContext(ctx).Notify(ctx, "notifications/message", map[string]any{
+				"level": "info",
+				"data":  "hello",
+			})
+			return api.CallToolResult{Content: []api.CallToolResultContentElem{}}, err
+		}).
+		WithTool(api.NewTool("sample"), func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			resp, err := jrpc2.ServerFromContext(ctx).Callback(ctx, "sampling/createMessage", api.CreateMessageRequestParams{
+				MaxTokens: 10,
+				Messages:  []api.SamplingMessage{},
+			})
+			if err != nil {
+				return api.CallToolResult{}, err
+			}
+			result := api.CreateMessageResult{}
+			if err := resp.UnmarshalResult(&result); err != nil {
+				return api.CallToolResult{}, err
+			}
+			return api.CallToolResult{
+				Content: []api.CallToolResultContentElem{
+					api.Text(result.Content.Text),
+				},
+			}, nil
+		}).
+		Build()
+
+	t.Run("initialize", func(t *testing.T) {
+		// when
+		cl := mcptest.NewClient(t, router)
+
+		// then
+		assert.Equal(t, "converse-mcp", cl.InitializeResult.ServerInfo.Name)
+	})
+
+	t.Run("call tool", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		result, err := cl.CallTool(context.Background(), "echo", map[string]any{"message": "hello"})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "hello")
+		mcptest.AssertStructuredContent(t, map[string]any{"message": "hello"}, result)
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		_, err := cl.CallTool(context.Background(), "notify", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertNotification(t, cl, time.Second, "notifications/message", map[string]any{
+			"level": "info",
+			"data":  "hello",
+		})
+	})
+
+	t.Run("successive notifications", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+		_, err := cl.CallTool(context.Background(), "notify", nil)
+		require.NoError(t, err)
+		mcptest.AssertNotification(t, cl, time.Second, "notifications/message", nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// when
+		_, err = cl.WaitForNotification(ctx, "notifications/message")
+
+		// then
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// when
+		_, err = cl.CallTool(context.Background(), "notify", nil)
+		require.NoError(t, err)
+
+		// then
+		mcptest.AssertNotification(t, cl, time.Second, "notifications/message", nil)
+		assert.Len(t, cl.Notifications(), 2)
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router, mcptest.WithSampling(func(_ context.Context, _ api.CreateMessageRequestParams) (api.CreateMessageResult, error) {
+			return api.CreateMessageResult{
+				Model: "test-model",
+				Role:  api.RoleAssistant,
+				Content: api.CreateMessageResultContent{
+					Type: "text",
+					Text: "sampled",
+				},
+			}, nil
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "sample", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "sampled")
+	})
+
+	t.Run("sampling not supported", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		_, err := cl.CallTool(context.Background(), "sample", nil)
+
+		// then
+		require.ErrorContains(t, err, "method 'sampling/createMessage' is not supported by the client")
+	})
+}
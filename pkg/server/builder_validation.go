@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// toolNameRegexp matches the valid tool names: between 1 and 128 ASCII letters, digits, underscores, hyphens and dots
+var toolNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// Validate checks the registrations of the builder, and returns an error which lists all the problems found:
+// duplicate names or URIs, invalid tool names, missing handlers, invalid prompt arguments and malformed tool schemas.
+// The returned error unwraps into the individual errors (see `errors.Join`).
+func (b *RouterBuilder) Validate() error {
+	errs := append([]error{}, b.errs...)
+	errs = append(errs, b.validateTools()...)
+	errs = append(errs, b.validatePrompts()...)
+	errs = append(errs, b.validateResources()...)
+	return errors.Join(errs...)
+}
+
+func (b *RouterBuilder) validateTools() []error {
+	errs := []error{}
+	names := map[string]bool{}
+	for i, h := range b.tools {
+		name := h.Tool.Name
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("tool #%d: missing name", i+1))
+		case !toolNameRegexp.MatchString(name):
+			errs = append(errs, fmt.Errorf("tool '%s': invalid name (expected 1 to 128 letters, digits, '_', '-' or '.')", name))
+		case names[name]:
+			errs = append(errs, fmt.Errorf("tool '%s': duplicate name", name))
+		}
+		names[name] = true
+		if h.Handle == nil {
+			errs = append(errs, fmt.Errorf("tool '%s': missing handler", name))
+		}
+		if h.Tool.InputSchema.Type != api.TypeObject {
+			errs = append(errs, fmt.Errorf("tool '%s': input schema must be of type 'object', not '%s'", name, h.Tool.InputSchema.Type))
+		}
+		errs = append(errs, validateToolSchema(name, "input", h.Tool.InputSchema, h.Tool.InputSchema.Properties, h.Tool.InputSchema.Required)...)
+		if s := h.Tool.OutputSchema; s != nil {
+			errs = append(errs, validateToolSchema(name, "output", s, s.Properties, s.Required)...)
+		}
+	}
+	return errs
+}
+
+func validateToolSchema(name, kind string, schema any, props map[string]map[string]any, required []string) []error {
+	errs := []error{}
+	if _, err := api.CompileSchema(schema); err != nil {
+		errs = append(errs, fmt.Errorf("tool '%s': %s schema: %w", name, kind, err))
+	}
+	for _, r := range required {
+		if _, ok := props[r]; !ok {
+			errs = append(errs, fmt.Errorf("tool '%s': %s schema: required property '%s' is not declared", name, kind, r))
+		}
+	}
+	return errs
+}
+
+func (b *RouterBuilder) validatePrompts() []error {
+	errs := []error{}
+	names := map[string]bool{}
+	for i, h := range b.prompts {
+		name := h.Prompt.Name
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("prompt #%d: missing name", i+1))
+		case names[name]:
+			errs = append(errs, fmt.Errorf("prompt '%s': duplicate name", name))
+		}
+		names[name] = true
+		if h.Handle == nil {
+			errs = append(errs, fmt.Errorf("prompt '%s': missing handler", name))
+		}
+		args := map[string]bool{}
+		for j, a := range h.Prompt.Arguments {
+			switch {
+			case a.Name == "":
+				errs = append(errs, fmt.Errorf("prompt '%s': argument #%d: missing name", name, j+1))
+			case args[a.Name]:
+				errs = append(errs, fmt.Errorf("prompt '%s': argument '%s': duplicate name", name, a.Name))
+			}
+			args[a.Name] = true
+		}
+	}
+	return errs
+}
+
+func (b *RouterBuilder) validateResources() []error {
+	errs := []error{}
+	uris := map[string]bool{}
+	for i, h := range b.resources {
+		uri := h.Resource.Uri
+		switch {
+		case uri == "":
+			errs = append(errs, fmt.Errorf("resource #%d: missing URI", i+1))
+		case uris[uri]:
+			errs = append(errs, fmt.Errorf("resource '%s': duplicate URI", uri))
+		}
+		uris[uri] = true
+		if h.Resource.Name == "" {
+			errs = append(errs, fmt.Errorf("resource '%s': missing name", uri))
+		}
+		if h.Handle == nil {
+			errs = append(errs, fmt.Errorf("resource '%s': missing handler", uri))
+		}
+	}
+	prefixes := []string{}
+	for i, r := range b.resourceRoutes {
+		desc := fmt.Sprintf("resource matcher #%d", i+1)
+		if r.Prefix != "" {
+			desc = fmt.Sprintf("resource prefix '%s'", r.Prefix)
+			if slices.Contains(prefixes, r.Prefix) {
+				errs = append(errs, fmt.Errorf("%s: duplicate prefix", desc))
+			}
+			prefixes = append(prefixes, r.Prefix)
+		}
+		if r.Match == nil {
+			errs = append(errs, fmt.Errorf("%s: missing matcher", desc))
+		}
+		if r.Handle == nil {
+			errs = append(errs, fmt.Errorf("%s: missing handler", desc))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+
+	logger := slog.New(slog.DiscardHandler)
+
+	t.Run("valid", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithPrompt(api.NewPrompt("my-prompt").WithArgument("topic", "Topic", "the topic", true), EmptyPromptHandle).
+			WithResource(api.NewResource("my-resource", "file:///my-resource"), EmptyResourceHandle).
+			WithResourcePrefix("file:///docs/", EmptyResourceHandle).
+			WithTool(api.NewTool("my_tool.v2").WithInputProperty("query", api.TypeString, "the query", true), EmptyToolHandle)
+
+		// when
+		err := b.Validate()
+
+		// then
+		require.NoError(t, err)
+		assert.NotPanics(t, func() { b.MustBuild() })
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		// given
+		invalidSchema := api.NewTool("invalid-schema").WithInputProp("query", api.String().Pattern("("), true)
+		invalidSchema.InputSchema.Required = append(invalidSchema.InputSchema.Required, "limit")
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithPrompt(api.NewPrompt("my-prompt").
+				WithArgument("topic", "Topic", "the topic", true).
+				WithArgument("topic", "Topic", "the topic", false).
+				WithArgument("", "", "", false), EmptyPromptHandle).
+			WithPrompt(api.NewPrompt("my-prompt"), nil).
+			WithResource(api.NewResource("first", "file:///README.md"), EmptyResourceHandle).
+			WithResource(api.NewResource("", "file:///README.md"), EmptyResourceHandle).
+			WithTool(api.NewTool(""), EmptyToolHandle).
+			WithTool(api.NewTool("my tool"), EmptyToolHandle).
+			WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+			WithTool(api.NewTool("my-tool"), nil).
+			WithTool(invalidSchema, EmptyToolHandle)
+
+		// when
+		err := b.Validate()
+
+		// then
+		require.EqualError(t, err, `tool #1: missing name
+tool 'my tool': invalid name (expected 1 to 128 letters, digits, '_', '-' or '.')
+tool 'my-tool': duplicate name
+tool 'my-tool': missing handler
+tool 'invalid-schema': input schema: invalid schema: invalid pattern '(': error parsing regexp: missing closing ): `+"`(`"+`
+tool 'invalid-schema': input schema: required property 'limit' is not declared
+prompt 'my-prompt': argument 'topic': duplicate name
+prompt 'my-prompt': argument #3: missing name
+prompt 'my-prompt': duplicate name
+prompt 'my-prompt': missing handler
+resource 'file:///README.md': duplicate URI
+resource 'file:///README.md': missing name`)
+		assert.Panics(t, func() { b.MustBuild() })
+	})
+
+	t.Run("build without validation", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+			WithTool(api.NewTool("my-tool"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				return api.CallToolResult{Content: []api.CallToolResultContentElem{api.Text("second")}}, nil
+			})
+		require.Error(t, b.Validate())
+
+		// when
+		router := b.Build()
+
+		// then the last registration wins
+		cl := mcptest.NewClient(t, router)
+		result, err := cl.CallTool(context.Background(), "my-tool", nil)
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "second")
+	})
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/channel"
+)
+
+// ErrRequestsUnsupported is returned by `Sample` and `Elicit` when the transport cannot send requests to the client
+// (eg: when the client of a Streamable HTTP session did not open an event stream)
+var ErrRequestsUnsupported = errors.New("the transport does not support requests to the client")
+
+// clientRequestIDPrefix is the prefix of the IDs of the requests sent by `clientRequests`, which are strings so that
+// they do not collide with the integer IDs of the requests sent with `jrpc2.Server.Callback`
+const clientRequestIDPrefix = "converse-mcp-"
+
+// clientRequests sends the requests of `Sample` and `Elicit` to the client of a session, and dispatches the responses
+// of the client. Unlike `jrpc2.Server.Callback`, it knows the IDs of the requests, so that the client can be notified
+// when a request is abandoned.
+type clientRequests struct {
+	mu      sync.Mutex
+	send    func(msg []byte) error // set by the transport, see `attach`
+	lastID  int64
+	pending map[api.RequestId]chan clientResponse
+}
+
+func newClientRequests() *clientRequests {
+	return &clientRequests{
+		pending: map[api.RequestId]chan clientResponse{},
+	}
+}
+
+// clientMessage is a request or a notification sent to the client
+type clientMessage struct {
+	JSONRPC string         `json:"jsonrpc"`
+	ID      *api.RequestId `json:"id,omitempty"`
+	Method  string         `json:"method"`
+	Params  any            `json:"params,omitempty"`
+}
+
+// clientResponse is a message received from the client, which is a response if it has an ID but no method
+type clientResponse struct {
+	ID     *api.RequestId  `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *jrpc2.Error    `json:"error"`
+}
+
+// attach sets the func with which the messages are sent to the client
+func (r *clientRequests) attach(send func(msg []byte) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.send = send
+}
+
+func (r *clientRequests) attached() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.send != nil
+}
+
+// call sends a request to the client and waits for its result. When the context ends before the response is
+// received, the request is abandoned and the client is notified with a `notifications/cancelled` notification.
+// The errors returned by the client are returned as `*jrpc2.Error`.
+func (r *clientRequests) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	r.mu.Lock()
+	r.lastID++
+	id := api.StringRequestId(clientRequestIDPrefix + strconv.FormatInt(r.lastID, 10))
+	rsp := make(chan clientResponse, 1)
+	r.pending[id] = rsp
+	r.mu.Unlock()
+	if err := r.sendMessage(clientMessage{ID: &id, Method: method, Params: params}); err != nil {
+		r.forget(id)
+		return nil, err
+	}
+	select {
+	case rsp := <-rsp:
+		if rsp.Error != nil {
+			return nil, rsp.Error
+		}
+		return rsp.Result, nil
+	case <-ctx.Done():
+		r.forget(id)
+		reason := ctx.Err().Error()
+		// best effort: the request is abandoned anyways
+		_ = r.sendMessage(clientMessage{Method: "notifications/cancelled", Params: api.CancelledNotificationParams{
+			RequestId: id,
+			Reason:    &reason,
+		}})
+		return nil, ctx.Err()
+	}
+}
+
+func (r *clientRequests) forget(id api.RequestId) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, id)
+}
+
+func (r *clientRequests) sendMessage(m clientMessage) error {
+	r.mu.Lock()
+	send := r.send
+	r.mu.Unlock()
+	if send == nil {
+		return ErrRequestsUnsupported
+	}
+	m.JSONRPC = "2.0"
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode the '%s' message: %w", m.Method, err)
+	}
+	return send(msg)
+}
+
+// deliver dispatches the responses to the requests sent by `call` found in the given message (or batch of messages),
+// and returns the other messages, or `nil` if there are none. The responses to the requests which were abandoned are
+// dropped.
+func (r *clientRequests) deliver(msg []byte) []byte {
+	if !bytes.Contains(msg, []byte(clientRequestIDPrefix)) {
+		return msg
+	}
+	data := bytes.TrimSpace(msg)
+	batch := len(data) > 0 && data[0] == '['
+	if !batch {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return msg // left to the server, which reports the error
+	}
+	others := make([]json.RawMessage, 0, len(elems))
+	for _, elem := range elems {
+		rsp := clientResponse{}
+		if err := json.Unmarshal(elem, &rsp); err != nil || !r.owns(rsp) {
+			others = append(others, elem)
+			continue
+		}
+		r.mu.Lock()
+		if c, found := r.pending[*rsp.ID]; found {
+			delete(r.pending, *rsp.ID)
+			c <- rsp
+		}
+		r.mu.Unlock()
+	}
+	switch {
+	case len(others) == len(elems):
+		return msg
+	case len(others) == 0:
+		return nil
+	case !batch:
+		return others[0]
+	}
+	rest, err := json.Marshal(others)
+	if err != nil {
+		return msg
+	}
+	return rest
+}
+
+// owns checks if the given message is a response to a request sent by `call`
+func (r *clientRequests) owns(rsp clientResponse) bool {
+	return rsp.Method == "" && rsp.ID != nil && rsp.ID.IsString() && strings.HasPrefix(rsp.ID.String(), clientRequestIDPrefix)
+}
+
+// NewSessionChannel returns a channel which sends and receives the messages of the given channel, and on which
+// `Sample` and `Elicit` send their requests to the client of the session held by the given context
+// (see `NewSessionContext`). The responses of the client to these requests are not received by the server.
+// If the server of the session is not started on such a channel, these requests are sent with
+// `jrpc2.Server.Callback`, and the client is not notified when they are abandoned.
+func NewSessionChannel(ctx context.Context, ch channel.Channel) channel.Channel {
+	s := sessionFromContext(ctx)
+	if s == nil {
+		return ch
+	}
+	c := &sessionChannel{
+		Channel:  ch,
+		requests: s.requests,
+	}
+	s.requests.attach(c.Send)
+	return c
+}
+
+type sessionChannel struct {
+	channel.Channel
+	mu       sync.Mutex // serializes the messages sent by the server and by `clientRequests`
+	requests *clientRequests
+}
+
+func (c *sessionChannel) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Channel.Send(msg)
+}
+
+func (c *sessionChannel) Recv() ([]byte, error) {
+	for {
+		msg, err := c.Channel.Recv()
+		if err != nil {
+			return msg, err
+		}
+		if msg = c.requests.deliver(msg); msg != nil {
+			return msg, nil
+		}
+	}
+}
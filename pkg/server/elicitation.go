@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ErrElicitationNotSupported is returned by `Elicit` when the client did not declare the `elicitation` capability
+var ErrElicitationNotSupported = errors.New("the client does not support elicitation")
+
+// ElicitationResponse is the response of the user to an `elicitation/create` request
+type ElicitationResponse struct {
+	// Action is the action of the user: `accept`, `decline` or `cancel`
+	Action api.ElicitResultAction
+	// Content is the input of the user, when the request was accepted. It conforms to the requested schema.
+	Content map[string]any
+}
+
+// Accepted returns true if the user submitted the requested input
+func (r ElicitationResponse) Accepted() bool {
+	return r.Action == api.ElicitResultActionAccept
+}
+
+// Declined returns true if the user explicitly declined the request
+func (r ElicitationResponse) Declined() bool {
+	return r.Action == api.ElicitResultActionDecline
+}
+
+// Cancelled returns true if the user dismissed the request without making a choice
+func (r ElicitationResponse) Cancelled() bool {
+	return r.Action == api.ElicitResultActionCancel
+}
+
+// Decode decodes the content into the given value (eg: a pointer to a struct whose JSON field names match the
+// properties of the requested schema). It returns an error if the request was not accepted.
+func (r ElicitationResponse) Decode(v any) error {
+	if !r.Accepted() {
+		return fmt.Errorf("no content to decode: elicitation request was not accepted (action: '%s')", r.Action)
+	}
+	data, err := json.Marshal(r.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decode the elicitation content: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode the elicitation content: %w", err)
+	}
+	return nil
+}
+
+// Elicit asks the user for some structured input with an `elicitation/create` request, sent on the session of the
+// request being handled (eg: in a tool handler), and waits for the response.
+// It returns `ErrElicitationNotSupported` if the client did not declare the `elicitation` capability, an error wrapping
+// `ErrRequestsUnsupported` if the transport cannot send requests to the client (eg: on Streamable HTTP, when the
+// client did not open an event stream), and an error if the content of an accepted request does not conform to the
+// schema. When the context ends before the response is received, the request is abandoned and the client is notified
+// with a `notifications/cancelled` notification, so that it can dismiss the prompt.
+func Elicit(ctx context.Context, message string, schema api.ElicitationSchema) (ElicitationResponse, error) {
+	capabilities, _ := ClientCapabilitiesFromContext(ctx)
+	if capabilities.Elicitation == nil {
+		return ElicitationResponse{}, ErrElicitationNotSupported
+	}
+	requested := schema.Build()
+	s, err := api.CompileSchema(requested)
+	if err != nil {
+		return ElicitationResponse{}, err
+	}
+	result := api.ElicitResult{}
+	if err := callClient(ctx, "elicitation/create", api.ElicitRequestParams{
+		Message:         message,
+		RequestedSchema: requested,
+	}, &result); err != nil {
+		return ElicitationResponse{}, err
+	}
+	if result.Action != api.ElicitResultActionAccept {
+		// the content is ignored when the request was declined or cancelled
+		return ElicitationResponse{Action: result.Action}, nil
+	}
+	content := result.Content
+	if content == nil {
+		content = map[string]any{}
+	}
+	if err := s.Validate(content); err != nil {
+		return ElicitationResponse{}, fmt.Errorf("invalid 'elicitation/create' result: %w", err)
+	}
+	return ElicitationResponse{Action: result.Action, Content: content}, nil
+}
@@ -0,0 +1,232 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicit(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	schema := api.NewElicitationSchema().
+		WithProperty("name", api.StringInput("Name").WithMinLength(1), true).
+		WithProperty("age", api.IntegerInput("Age").WithMinimum(0), false)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("register"),
+			func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+				if timeout, ok := params.Arguments["timeout"].(float64); ok {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+					defer cancel()
+				}
+				rsp, err := server.Elicit(ctx, "Please tell us about you", schema)
+				if err != nil {
+					return api.CallToolResult{}, err
+				}
+				if !rsp.Accepted() {
+					return api.CallToolResult{
+						Content: []api.CallToolResultContentElem{api.Text(fmt.Sprintf("not registered (%s)", rsp.Action))},
+					}, nil
+				}
+				user := struct {
+					Name string `json:"name"`
+					Age  int    `json:"age"`
+				}{}
+				if err := rsp.Decode(&user); err != nil {
+					return api.CallToolResult{}, err
+				}
+				return api.CallToolResult{
+					Content: []api.CallToolResultContentElem{api.Text(fmt.Sprintf("registered %s (%d)", user.Name, user.Age))},
+				}, nil
+			}).
+		Build()
+
+	t.Run("accept", func(t *testing.T) {
+		// given
+		var received api.ElicitRequestParams
+		cl := mcptest.NewClient(t, router, mcptest.WithElicitation(func(_ context.Context, params api.ElicitRequestParams) (api.ElicitResult, error) {
+			received = params
+			return api.ElicitResult{
+				Action:  api.ElicitResultActionAccept,
+				Content: map[string]any{"name": "John", "age": 42},
+			}, nil
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "register", map[string]any{})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "registered John (42)")
+		assert.Equal(t, "Please tell us about you", received.Message)
+		assert.Equal(t, []string{"name"}, received.RequestedSchema.Required)
+		assert.Contains(t, received.RequestedSchema.Properties, "age")
+	})
+
+	for _, action := range []api.ElicitResultAction{api.ElicitResultActionDecline, api.ElicitResultActionCancel} {
+		t.Run(string(action), func(t *testing.T) {
+			// given
+			cl := mcptest.NewClient(t, router, mcptest.WithElicitation(func(_ context.Context, _ api.ElicitRequestParams) (api.ElicitResult, error) {
+				return api.ElicitResult{Action: action}, nil
+			}))
+
+			// when
+			result, err := cl.CallTool(context.Background(), "register", map[string]any{})
+
+			// then
+			require.NoError(t, err)
+			mcptest.AssertTextContent(t, result, fmt.Sprintf("not registered (%s)", action))
+		})
+	}
+
+	t.Run("invalid content", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router, mcptest.WithElicitation(func(_ context.Context, _ api.ElicitRequestParams) (api.ElicitResult, error) {
+			return api.ElicitResult{
+				Action:  api.ElicitResultActionAccept,
+				Content: map[string]any{"age": -1},
+			}, nil
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "register", map[string]any{})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		assert.Contains(t, result.Content[0].(api.TextContent).Text, "invalid 'elicitation/create' result")
+	})
+
+	t.Run("elicitation not supported", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		result, err := cl.CallTool(context.Background(), "register", map[string]any{})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		mcptest.AssertTextContent(t, result, server.ErrElicitationNotSupported.Error())
+	})
+
+	t.Run("abandon on cancellation", func(t *testing.T) {
+		// given
+		cancelled := make(chan error, 1)
+		cl := mcptest.NewClient(t, router, mcptest.WithElicitation(func(ctx context.Context, _ api.ElicitRequestParams) (api.ElicitResult, error) {
+			<-ctx.Done() // cancelled when the client is notified, so that the prompt can be dismissed
+			cancelled <- ctx.Err()
+			return api.ElicitResult{}, ctx.Err()
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "register", map[string]any{"timeout": 50})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		mcptest.AssertTextContent(t, result, "'elicitation/create' request abandoned: context deadline exceeded")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = cl.WaitForNotification(ctx, "notifications/cancelled")
+		require.NoError(t, err)
+		select {
+		case err := <-cancelled:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-ctx.Done():
+			assert.Fail(t, "the request was not cancelled on the client side")
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+
+		t.Run("accept", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"elicitation":{}}`)
+			session.openEventStream()
+			results := session.callToolAsync("register", map[string]any{})
+
+			// when
+			req := session.receive()
+			status := session.respond(req.ID, api.ElicitResult{
+				Action:  api.ElicitResultActionAccept,
+				Content: map[string]any{"name": "John", "age": 42},
+			})
+
+			// then
+			assert.Equal(t, "elicitation/create", req.Method)
+			received := api.ElicitRequestParams{}
+			require.NoError(t, json.Unmarshal(req.Params, &received))
+			assert.Equal(t, "Please tell us about you", received.Message)
+			assert.Equal(t, http.StatusAccepted, status)
+			result := <-results
+			require.NoError(t, result.err)
+			mcptest.AssertTextContent(t, result.CallToolResult, "registered John (42)")
+		})
+
+		t.Run("no event stream", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"elicitation":{}}`)
+
+			// when
+			result := <-session.callToolAsync("register", map[string]any{})
+
+			// then
+			require.NoError(t, result.err)
+			require.NotNil(t, result.IsError)
+			assert.True(t, *result.IsError)
+			mcptest.AssertTextContent(t, result.CallToolResult, "'elicitation/create' request not sent: "+
+				"the transport does not support requests to the client: no event stream opened by the client")
+		})
+
+		t.Run("abandon on cancellation", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"elicitation":{}}`)
+			session.openEventStream()
+
+			// when
+			results := session.callToolAsync("register", map[string]any{"timeout": 50})
+
+			// then
+			req := session.receive()
+			assert.Equal(t, "elicitation/create", req.Method)
+			n := session.receive()
+			assert.Equal(t, "notifications/cancelled", n.Method)
+			params := struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}{}
+			require.NoError(t, json.Unmarshal(n.Params, &params))
+			assert.JSONEq(t, string(req.ID), string(params.RequestID))
+			result := <-results
+			require.NoError(t, result.err)
+			mcptest.AssertTextContent(t, result.CallToolResult, "'elicitation/create' request abandoned: context deadline exceeded")
+		})
+	})
+
+	t.Run("decode a declined response", func(t *testing.T) {
+		// given
+		rsp := server.ElicitationResponse{Action: api.ElicitResultActionDecline}
+
+		// when
+		err := rsp.Decode(&map[string]any{})
+
+		// then
+		require.EqualError(t, err, "no content to decode: elicitation request was not accepted (action: 'decline')")
+	})
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ResourceNotFoundCode is the JSON-RPC error code returned when the requested resource does not exist.
+// The error data contains the `uri` of the resource.
+const ResourceNotFoundCode jrpc2.Code = -32002
+
+// ToolNotFoundError returns the `invalid params` protocol error for a call to an unknown tool.
+// The error data contains the `name` of the tool.
+func ToolNotFoundError(name string) *jrpc2.Error {
+	return jrpc2.Errorf(jrpc2.InvalidParams, "unknown tool: '%s'", name).
+		WithData(map[string]any{
+			"name": name,
+		})
+}
+
+// PromptNotFoundError returns the `invalid params` protocol error for a request of an unknown prompt.
+// The error data contains the `name` of the prompt.
+func PromptNotFoundError(name string) *jrpc2.Error {
+	return jrpc2.Errorf(jrpc2.InvalidParams, "unknown prompt: '%s'", name).
+		WithData(map[string]any{
+			"name": name,
+		})
+}
+
+// MissingPromptArgumentsError returns the `invalid params` protocol error for a request of a prompt without
+// some of its required arguments. The error data contains the `name` of the prompt and the `missing` arguments.
+func MissingPromptArgumentsError(name string, missing []string) *jrpc2.Error {
+	return jrpc2.Errorf(jrpc2.InvalidParams, "missing required arguments for prompt '%s': '%s'", name, strings.Join(missing, "', '")).
+		WithData(map[string]any{
+			"name":    name,
+			"missing": missing,
+		})
+}
+
+// ResourceNotFoundError returns the protocol error for a read of an unknown resource
+func ResourceNotFoundError(uri string) *jrpc2.Error {
+	return jrpc2.Errorf(ResourceNotFoundCode, "resource not found: '%s'", uri).
+		WithData(map[string]any{
+			"uri": uri,
+		})
+}
+
+// invalidParamsError returns the `invalid params` protocol error for request parameters which cannot be decoded
+func invalidParamsError(req *jrpc2.Request, err error) *jrpc2.Error {
+	return jrpc2.Errorf(jrpc2.InvalidParams, "invalid '%s' request parameters: %v", req.Method(), err)
+}
+
+// ToolErrorResult returns a result with the `isError` flag set and the message of the given error,
+// so that the LLM can see the error and self-correct
+func ToolErrorResult(err error) api.CallToolResult {
+	return newToolErrorResult(err.Error())
+}
+
+// ToolResultFromError converts an error returned by a tool handler: protocol errors (`*jrpc2.Error`) are returned
+// as-is, since they are meant for the client (eg: invalid params), while the other errors are tool failures which are
+// reported in a result with the `isError` flag set (see `ToolErrorResult`).
+func ToolResultFromError(err error) (api.CallToolResult, error) {
+	var rpcErr *jrpc2.Error
+	if errors.As(err, &rpcErr) {
+		return api.CallToolResult{}, rpcErr
+	}
+	return ToolErrorResult(err), nil
+}
+
+// newToolErrorResult returns a result with the `isError` flag set, so that the LLM can see the error and self-correct
+func newToolErrorResult(msg string) api.CallToolResult {
+	return api.CallToolResult{
+		Content: []api.CallToolResultContentElem{
+			api.Text(msg),
+		},
+		IsError: api.BoolPtr(true),
+	}
+}
@@ -0,0 +1,94 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("fail"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			return api.CallToolResult{}, errors.New("mock failure")
+		}).
+		WithTool(api.NewTool("reject"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			return api.CallToolResult{}, jrpc2.Errorf(jrpc2.InvalidParams, "mock rejection")
+		}).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	t.Run("unknown tool", func(t *testing.T) {
+		// when
+		_, err := cl.CallTool(context.Background(), "unknown", nil)
+
+		// then
+		assertRPCError(t, err, jrpc2.InvalidParams, "unknown tool: 'unknown'", `{"name":"unknown"}`)
+	})
+
+	t.Run("unknown prompt", func(t *testing.T) {
+		// when
+		_, err := cl.GetPrompt(context.Background(), "unknown", nil)
+
+		// then
+		assertRPCError(t, err, jrpc2.InvalidParams, "unknown prompt: 'unknown'", `{"name":"unknown"}`)
+	})
+
+	t.Run("unknown resource", func(t *testing.T) {
+		// when
+		_, err := cl.ReadResource(context.Background(), "file:///unknown")
+
+		// then
+		assertRPCError(t, err, server.ResourceNotFoundCode, "resource not found: 'file:///unknown'", `{"uri":"file:///unknown"}`)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		// when
+		_, err := cl.Call(context.Background(), "tools/call", map[string]any{"name": 1})
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, jrpc2.InvalidParams, rpcErr.Code)
+	})
+
+	t.Run("tool failure", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "fail", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolError(t, result)
+		mcptest.AssertTextContent(t, result, "mock failure")
+	})
+
+	t.Run("tool protocol error", func(t *testing.T) {
+		// when
+		_, err := cl.CallTool(context.Background(), "reject", nil)
+
+		// then
+		assertRPCError(t, err, jrpc2.InvalidParams, "mock rejection", "")
+	})
+}
+
+func assertRPCError(t *testing.T, err error, code jrpc2.Code, message, data string) {
+	t.Helper()
+	var rpcErr *jrpc2.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, code, rpcErr.Code)
+	assert.Equal(t, message, rpcErr.Message)
+	if data != "" {
+		assert.JSONEq(t, data, string(rpcErr.Data))
+	}
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// FSProvider provides the regular files of a directory or of an `fs.FS` (eg: an `embed.FS`) as `file://` resources.
+// The URIs which do not designate a file of the tree (eg: with `..` segments) are rejected, and so are the symbolic
+// links which point outside of the directory (or all symbolic links for an `fs.FS`).
+type FSProvider struct {
+	fsys fs.FS
+	// base is the path of the URIs of the root of the tree, with a trailing slash
+	base string
+	// root is set for the directories, to resolve the symbolic links within the directory
+	root *os.Root
+	mu   sync.Mutex
+	// the state of the files when they were last checked, to detect the changes
+	files map[string]fileState
+}
+
+// fileState is the state of a file used to detect its changes
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewDirProvider returns a provider for the files of the given directory, whose URIs are the `file://` URLs of their
+// absolute paths. Use `Close` to release the directory when the provider is no longer used.
+func NewDirProvider(dir string) (*FSProvider, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory '%s': %w", dir, err)
+	}
+	root, err := os.OpenRoot(abs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory '%s': %w", dir, err)
+	}
+	p := newFSProvider(root.FS(), filepath.ToSlash(abs))
+	p.root = root
+	p.files, _ = p.stat()
+	return p, nil
+}
+
+// NewFSProvider returns a provider for the files of the given file system, whose URIs are `file://` URLs whose path
+// starts with the given base path (eg: `file:///docs/guide.md` for the `guide.md` file and the `/docs` base path).
+func NewFSProvider(fsys fs.FS, base string) *FSProvider {
+	p := newFSProvider(fsys, base)
+	p.files, _ = p.stat()
+	return p
+}
+
+func newFSProvider(fsys fs.FS, base string) *FSProvider {
+	base = path.Clean("/" + base)
+	if base != "/" {
+		base += "/"
+	}
+	return &FSProvider{
+		fsys: fsys,
+		base: base,
+	}
+}
+
+// Close releases the directory of the provider
+func (p *FSProvider) Close() error {
+	if p.root != nil {
+		return p.root.Close()
+	}
+	return nil
+}
+
+// Prefix returns the URI of the root of the tree
+func (p *FSProvider) Prefix() string {
+	return p.uri("")
+}
+
+func (p *FSProvider) uri(name string) string {
+	return (&url.URL{
+		Scheme: "file",
+		Path:   p.base + name,
+	}).String()
+}
+
+// name returns the path in the tree of the file with the given URI, or an error if the URI does not designate
+// a regular file of the tree
+func (p *FSProvider) name(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Host != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", ResourceNotFoundError(uri)
+	}
+	name, ok := strings.CutPrefix(u.Path, p.base)
+	if !ok || !fs.ValidPath(name) || name == "." || strings.Contains(name, `\`) {
+		return "", ResourceNotFoundError(uri)
+	}
+	if p.root == nil {
+		// the symbolic links of an `fs.FS` may point anywhere (eg: with `os.DirFS`)
+		if err := p.checkNoSymlink(name); err != nil {
+			return "", ResourceNotFoundError(uri)
+		}
+	}
+	info, err := fs.Stat(p.fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", ResourceNotFoundError(uri)
+	}
+	return name, nil
+}
+
+// checkNoSymlink returns an error if the given path or one of its parent directories is a symbolic link
+func (p *FSProvider) checkNoSymlink(name string) error {
+	dir := "."
+	for _, elem := range strings.Split(name, "/") {
+		entries, err := fs.ReadDir(p.fsys, dir)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, e := range entries {
+			if e.Name() == elem {
+				if e.Type()&fs.ModeSymlink != 0 {
+					return fmt.Errorf("'%s' is a symbolic link", path.Join(dir, elem))
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fs.ErrNotExist
+		}
+		dir = path.Join(dir, elem)
+	}
+	return nil
+}
+
+// ListResources returns the regular files of the tree, in lexical order
+func (p *FSProvider) ListResources(_ context.Context) ([]api.Resource, error) {
+	resources := []api.Resource{}
+	err := p.walk(func(name string, info fs.FileInfo) {
+		r := api.NewResource(name, p.uri(name)).WithSize(int(info.Size()))
+		if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+			r = r.WithMimeType(mimeType)
+		}
+		resources = append(resources, r)
+	})
+	return resources, err
+}
+
+// walk calls the given func for each regular file of the tree. Symbolic links are skipped, except in a directory,
+// where the links to regular files within the directory are followed.
+func (p *FSProvider) walk(f func(name string, info fs.FileInfo)) error {
+	return fs.WalkDir(p.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			f(name, info)
+		case d.Type()&fs.ModeSymlink != 0 && p.root != nil:
+			// fails if the link points outside of the directory
+			if info, err := fs.Stat(p.fsys, name); err == nil && info.Mode().IsRegular() {
+				f(name, info)
+			}
+		}
+		return nil
+	})
+}
+
+// ReadResource returns the content of the file with the given URI, as text for the textual MIME types, as a blob
+// otherwise. The MIME type is detected from the extension of the file, or from its content.
+func (p *FSProvider) ReadResource(_ context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+	name, err := p.name(params.Uri)
+	if err != nil {
+		return api.ReadResourceResult{}, err
+	}
+	data, err := fs.ReadFile(p.fsys, name)
+	if err != nil {
+		return api.ReadResourceResult{}, fmt.Errorf("error while reading resource '%s': %w", params.Uri, err)
+	}
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	contents := api.ReadResourceResultContentsElem{
+		Uri:      params.Uri,
+		MimeType: &mimeType,
+	}
+	if isTextMimeType(mimeType) && utf8.Valid(data) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = api.EncodeBase64(data)
+	}
+	return api.ReadResourceResult{
+		Contents: []api.ReadResourceResultContentsElem{contents},
+	}, nil
+}
+
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+yaml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/toml",
+		"application/javascript", "application/x-sh", "application/sql":
+		return true
+	}
+	return false
+}
+
+// Check detects the files which changed since the previous check: the sessions which subscribed to the modified files
+// are notified, and all the sessions are notified if files were added or removed
+func (p *FSProvider) Check(s *Subscriptions) error {
+	files, err := p.stat()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	previous := p.files
+	p.files = files
+	p.mu.Unlock()
+	for name, state := range files {
+		if prev, found := previous[name]; found && prev != state {
+			s.NotifyResourceUpdated(p.uri(name))
+		}
+	}
+	// compare the names only
+	if !maps.EqualFunc(files, previous, func(_, _ fileState) bool { return true }) {
+		s.Notify("notifications/resources/list_changed", nil)
+	}
+	return nil
+}
+
+// Watch checks the files at the given interval until the context is done (see `Check`)
+func (p *FSProvider) Watch(ctx context.Context, interval time.Duration, s *Subscriptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Check(s); err != nil {
+				s.logger.Error("failed to check the files", "prefix", p.Prefix(), "error", err)
+			}
+		}
+	}
+}
+
+func (p *FSProvider) stat() (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := p.walk(func(name string, info fs.FileInfo) {
+		files[name] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+	})
+	return files, err
+}
@@ -0,0 +1,196 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSProvider(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	fsys := fstest.MapFS{
+		"README.md":       {Data: []byte("# Docs")},
+		"api/schema.json": {Data: []byte(`{"type":"object"}`)},
+		"img/logo.png":    {Data: []byte("\x89PNG\r\n\x1a\n")},
+		"notes":           {Data: []byte("plain notes")},
+		"link":            {Data: []byte("README.md"), Mode: os.ModeSymlink},
+	}
+	p := server.NewFSProvider(fsys, "/docs")
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithResource(api.NewResource("motd", "text:///motd"), TextResourceHandle("hello")).
+		WithResourceProvider(p).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	t.Run("list", func(t *testing.T) {
+		// when
+		result, err := cl.ListResources(context.Background())
+
+		// then
+		require.NoError(t, err)
+		uris := []string{}
+		for _, r := range result.Resources {
+			uris = append(uris, r.Uri)
+		}
+		assert.Equal(t, []string{
+			"text:///motd",
+			"file:///docs/README.md",
+			"file:///docs/api/schema.json",
+			"file:///docs/img/logo.png",
+			"file:///docs/notes",
+		}, uris)
+		assert.Equal(t, "api/schema.json", result.Resources[2].Name)
+		assert.Equal(t, "application/json", *result.Resources[2].MimeType)
+		assert.Equal(t, 17, *result.Resources[2].Size)
+	})
+
+	t.Run("read text", func(t *testing.T) {
+		for uri, expected := range map[string]string{
+			"file:///docs/README.md":       "# Docs",
+			"file:///docs/api/schema.json": `{"type":"object"}`,
+			"file:///docs/notes":           "plain notes", // detected from the content
+		} {
+			t.Run(uri, func(t *testing.T) {
+				// when
+				result, err := cl.ReadResource(context.Background(), uri)
+
+				// then
+				require.NoError(t, err)
+				require.Len(t, result.Contents, 1)
+				assert.Equal(t, expected, result.Contents[0].Text)
+				assert.Empty(t, result.Contents[0].Blob)
+			})
+		}
+	})
+
+	t.Run("read blob", func(t *testing.T) {
+		// when
+		result, err := cl.ReadResource(context.Background(), "file:///docs/img/logo.png")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result.Contents, 1)
+		assert.Equal(t, "image/png", *result.Contents[0].MimeType)
+		assert.Equal(t, api.EncodeBase64([]byte("\x89PNG\r\n\x1a\n")), result.Contents[0].Blob)
+	})
+
+	t.Run("reject invalid paths", func(t *testing.T) {
+		for _, uri := range []string{
+			"file:///docs/../docs/README.md",
+			"file:///docs/%2e%2e/secret",
+			"file:///docs/api",
+			"file:///docs/link",
+			"file:///docs/missing.md",
+			"file://host/docs/README.md",
+		} {
+			t.Run(uri, func(t *testing.T) {
+				// when
+				_, err := cl.ReadResource(context.Background(), uri)
+
+				// then
+				var rpcErr *jrpc2.Error
+				require.ErrorAs(t, err, &rpcErr)
+				assert.Equal(t, server.ResourceNotFoundCode, rpcErr.Code)
+			})
+		}
+	})
+}
+
+func TestDirProvider(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "docs")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "guides"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guides", "intro.md"), []byte("# Intro"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "secret.txt"), []byte("secret"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join("guides", "intro.md"), filepath.Join(dir, "intro.md")))
+	require.NoError(t, os.Symlink(filepath.Join(tmp, "secret.txt"), filepath.Join(dir, "secret.txt")))
+	require.NoError(t, os.Symlink(tmp, filepath.Join(dir, "parent")))
+	p, err := server.NewDirProvider(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+	subscriptions := server.NewSubscriptions(logger)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithSubscriptions(subscriptions).
+		WithResourceProvider(p).
+		Build()
+	cl := mcptest.NewClient(t, router)
+	prefix := "file://" + filepath.ToSlash(dir) + "/"
+
+	t.Run("list", func(t *testing.T) {
+		// when
+		result, err := cl.ListResources(context.Background())
+
+		// then
+		require.NoError(t, err)
+		uris := []string{}
+		for _, r := range result.Resources {
+			uris = append(uris, r.Uri)
+		}
+		// links which point outside of the directory are skipped
+		assert.Equal(t, []string{prefix + "guides/intro.md", prefix + "intro.md"}, uris)
+	})
+
+	t.Run("follow links within the directory", func(t *testing.T) {
+		// when
+		result, err := cl.ReadResource(context.Background(), prefix+"intro.md")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "# Intro", result.Contents[0].Text)
+	})
+
+	t.Run("reject links outside of the directory", func(t *testing.T) {
+		for _, uri := range []string{prefix + "secret.txt", prefix + "parent/secret.txt", prefix + "../secret.txt"} {
+			t.Run(uri, func(t *testing.T) {
+				// when
+				_, err := cl.ReadResource(context.Background(), uri)
+
+				// then
+				var rpcErr *jrpc2.Error
+				require.ErrorAs(t, err, &rpcErr)
+				assert.Equal(t, server.ResourceNotFoundCode, rpcErr.Code)
+			})
+		}
+	})
+
+	t.Run("notify the changes", func(t *testing.T) {
+		// given
+		require.NoError(t, cl.Subscribe(context.Background(), prefix+"guides/intro.md"))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "guides", "intro.md"), []byte("# Updated intro"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "faq.md"), []byte("# FAQ"), 0o600))
+
+		// when
+		err := p.Check(subscriptions)
+
+		// then
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		n, err := cl.WaitForNotification(ctx, "notifications/resources/updated")
+		require.NoError(t, err)
+		params := api.ResourceUpdatedNotificationParams{}
+		require.NoError(t, n.UnmarshalParams(&params))
+		assert.Equal(t, prefix+"guides/intro.md", params.Uri)
+		_, err = cl.WaitForNotification(ctx, "notifications/resources/list_changed")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// eventStream sends the requests and notifications of the server to the client of a session, as Server-Sent Events
+// in the response to the `GET` request with which the client opened the stream. A session has at most one stream:
+// opening a new stream closes the previous one.
+type eventStream struct {
+	mu   sync.Mutex
+	w    http.ResponseWriter // nil when no stream is open
+	done chan struct{}       // closed when the stream is replaced or the session is closed
+}
+
+// send sends the given message in an event, or returns an error wrapping `ErrRequestsUnsupported` if no stream is open
+func (s *eventStream) send(msg []byte) error {
+	data := &bytes.Buffer{}
+	if err := json.Compact(data, msg); err != nil { // events cannot span multiple `data` lines
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return fmt.Errorf("%w: no event stream opened by the client", ErrRequestsUnsupported)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: message\ndata: %s\n\n", data.Bytes()); err != nil {
+		return fmt.Errorf("failed to send the event: %w", err)
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+// serve streams the events until the client disconnects, the stream is replaced or the session is closed
+func (s *eventStream) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		return // streaming is not supported by the response writer
+	}
+	done := make(chan struct{})
+	s.mu.Lock()
+	if s.done != nil {
+		close(s.done)
+	}
+	s.w, s.done = w, done
+	s.mu.Unlock()
+	select {
+	case <-r.Context().Done():
+	case <-done:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == done {
+		s.w, s.done = nil, nil
+	}
+}
+
+// open checks if the client opened a stream
+func (s *eventStream) open() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w != nil
+}
+
+// close ends the stream opened by the client, if any
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		close(s.done)
+	}
+	s.w, s.done = nil, nil
+}
+
+// acceptsEventStream checks if the `Accept` header of the request includes the `text/event-stream` media type
+func acceptsEventStream(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(v, ",") {
+			if mediaType, _, _ = strings.Cut(mediaType, ";"); strings.TrimSpace(mediaType) == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/creachadair/jrpc2"
+)
+
+const (
+	// DefaultMaxBodySize is the default maximum size of a request body, in bytes
+	DefaultMaxBodySize = 4 << 20
+	// DefaultMaxJSONDepth is the default maximum nesting depth of the JSON objects and arrays in a request body
+	DefaultMaxJSONDepth = 64
+	// DefaultMaxBatchLength is the default maximum number of requests in a batch
+	DefaultMaxBatchLength = 100
+)
+
+// WithMaxBodySize sets the maximum size of a request body, in bytes.
+// Larger requests are rejected with a `413 Request Entity Too Large` status.
+func WithMaxBodySize(size int64) HTTPOption {
+	return func(h *httpHandler) {
+		h.maxBodySize = size
+	}
+}
+
+// WithMaxJSONDepth sets the maximum nesting depth of the JSON objects and arrays in a request body.
+// Deeper requests are rejected with an `invalid request` JSON-RPC error.
+func WithMaxJSONDepth(depth int) HTTPOption {
+	return func(h *httpHandler) {
+		h.maxJSONDepth = depth
+	}
+}
+
+// WithMaxBatchLength sets the maximum number of requests in a batch.
+// Larger batches are rejected with an `invalid request` JSON-RPC error.
+func WithMaxBatchLength(length int) HTTPOption {
+	return func(h *httpHandler) {
+		h.maxBatchLength = length
+	}
+}
+
+// readBody reads and checks the body of the given request against the configured limits, and returns it along with
+// the parsed requests (which are nil if the body is not valid JSON, so that the parse error is reported by the bridge).
+// If the body exceeds a limit, the error response is written and the returned bool is `false`.
+func (h *httpHandler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, []*jrpc2.ParsedRequest, bool) {
+	if r.ContentLength > h.maxBodySize {
+		http.Error(w, fmt.Sprintf("request body exceeds %d bytes", h.maxBodySize), http.StatusRequestEntityTooLarge)
+		return nil, nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", h.maxBodySize), http.StatusRequestEntityTooLarge)
+			return nil, nil, false
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if err := checkDepth(body, h.maxJSONDepth); err != nil {
+		writeInvalidRequest(w, err.Error())
+		return nil, nil, false
+	}
+	reqs, err := jrpc2.ParseRequests(body)
+	if err != nil {
+		return body, nil, true
+	}
+	if len(reqs) > h.maxBatchLength {
+		writeInvalidRequest(w, fmt.Sprintf("batch exceeds the maximum length of %d requests", h.maxBatchLength))
+		return nil, nil, false
+	}
+	return body, reqs, true
+}
+
+// checkDepth checks that the nesting of objects and arrays in the given JSON data is not deeper than the given limit,
+// without decoding the data. It also returns an error if a closing bracket does not match any opening bracket, since
+// the nesting of the subsequent data cannot be checked.
+func checkDepth(data []byte, limit int) error {
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > limit {
+				return fmt.Errorf("request exceeds the maximum nesting depth of %d", limit)
+			}
+		case '}', ']':
+			depth--
+			if depth < 0 {
+				return errors.New("request is not valid JSON: unbalanced brackets")
+			}
+		}
+	}
+	return nil
+}
+
+// writeInvalidRequest writes a JSON-RPC `invalid request` error, which is not associated with any request ID
+func writeInvalidRequest(w http.ResponseWriter, msg string) {
+	body, _ := json.Marshal(map[string]any{
+		"jsonrpc": jrpc2.Version,
+		"id":      nil,
+		"error":   jrpc2.Errorf(jrpc2.InvalidRequest, "%s", msg),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,141 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPLimits(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+		Build()
+	h := server.NewHTTPHandler(router, logger,
+		server.WithMaxBodySize(1024),
+		server.WithMaxJSONDepth(8),
+		server.WithMaxBatchLength(2),
+	)
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "valid request",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"my-tool","arguments":{"a":[[1]]}}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "body too large",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + strings.Repeat("a", 1024) + `"}}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "nesting too deep",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"my-tool","arguments":{"a":` + strings.Repeat("[", 8) + strings.Repeat("]", 8) + `}}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "request exceeds the maximum nesting depth of 8",
+		},
+		{
+			name:           "unbalanced closing brackets",
+			body:           strings.Repeat("]", 8) + `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"my-tool","arguments":{"a":` + strings.Repeat("[", 12) + strings.Repeat("]", 12) + `}}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "request is not valid JSON: unbalanced brackets",
+		},
+		{
+			name:           "brackets in strings are ignored",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"my-tool","arguments":{"a":"` + strings.Repeat(`[{\"`, 16) + `"}}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "batch too long",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},
+				{"jsonrpc":"2.0","id":2,"method":"tools/list"},
+				{"jsonrpc":"2.0","id":3,"method":"tools/list"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "batch exceeds the maximum length of 2 requests",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewBufferString(testCase.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			// when
+			h.ServeHTTP(rec, req)
+
+			// then
+			require.Equal(t, testCase.expectedStatus, rec.Code)
+			if testCase.expectedError != "" {
+				resp := struct {
+					Error api.JSONRPCErrorError `json:"error"`
+				}{}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, -32600, resp.Error.Code)
+				assert.Equal(t, testCase.expectedError, resp.Error.Message)
+			}
+		})
+	}
+}
+
+func FuzzHTTPHandler(f *testing.F) {
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+		Build()
+	h := server.NewHTTPHandler(router, logger,
+		server.WithMaxBodySize(4096),
+		server.WithMaxJSONDepth(16),
+		server.WithMaxBatchLength(8),
+		server.WithMaxSessions(8),
+	)
+	for _, seed := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"my-tool"}}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+		`{"jsonrpc":"2.0","id":{"a":1},"method":"tools/list"}`,
+		`[]`,
+		`[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":"\u0000\"`,
+		strings.Repeat(`{"a":`, 64),
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	allowedStatuses := []int{
+		http.StatusOK,
+		http.StatusNoContent,
+		http.StatusBadRequest,
+		http.StatusRequestEntityTooLarge,
+		http.StatusServiceUnavailable,
+		http.StatusInternalServerError, // reported by the bridge on invalid JSON
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		assert.Contains(t, allowedStatuses, rec.Code)
+	})
+}
@@ -3,6 +3,10 @@ package server
 import (
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func LoggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
@@ -11,3 +15,116 @@ func LoggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// OriginValidationMiddleware rejects the requests whose `Origin` header does not match any of the allowed origins
+// with a `403 Forbidden` status, to prevent DNS rebinding attacks. Requests without an `Origin` header (ie: which were not
+// sent by a browser) are accepted.
+// An allowed origin is either `*`, an exact origin (eg: `https://example.com`) or an origin with a wildcard subdomain
+// (eg: `https://*.example.com`).
+func OriginValidationMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(origin, allowedOrigins) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins uses the same rules as the origin validation
+	AllowedOrigins []string
+	// AllowedMethods defaults to `GET`, `POST`, `DELETE` and `OPTIONS`
+	AllowedMethods []string
+	// AllowedHeaders defaults to the headers used by the MCP Streamable HTTP transport
+	AllowedHeaders []string
+	// ExposedHeaders always include the `Mcp-Session-Id` header
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is the duration for which the results of a preflight request can be cached (not sent if zero)
+	MaxAge time.Duration
+}
+
+var (
+	defaultCORSAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions}
+	defaultCORSAllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID", "Mcp-Protocol-Version", SessionIDHeader}
+)
+
+// CORSMiddleware handles the preflight (`OPTIONS`) requests and sets the CORS headers on the responses to the requests
+// from the allowed origins. Preflight requests from other origins are rejected with a `403 Forbidden` status, while
+// other requests are passed to the next handler without CORS headers, so that it can be combined with
+// the `OriginValidationMiddleware`.
+func CORSMiddleware(opts CORSOptions, next http.Handler) http.Handler {
+	allowedMethods := opts.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = defaultCORSAllowedMethods
+	}
+	allowedHeaders := opts.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = defaultCORSAllowedHeaders
+	}
+	exposedHeaders := opts.ExposedHeaders
+	if !slices.ContainsFunc(exposedHeaders, func(h string) bool { return strings.EqualFold(h, SessionIDHeader) }) {
+		exposedHeaders = append(slices.Clone(exposedHeaders), SessionIDHeader)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !originAllowed(origin, opts.AllowedOrigins) {
+			if preflight {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if opts.AllowCredentials || !slices.Contains(opts.AllowedOrigins, "*") {
+			// the wildcard is not allowed with credentials
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if opts.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if !slices.Contains(allowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+			http.Error(w, "method not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
+		if opts.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func originAllowed(origin string, allowedOrigins []string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// wildcard subdomain, eg: `https://*.example.com`
+		if scheme, domain, found := strings.Cut(allowed, "://*."); found {
+			if host, ok := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://"); ok &&
+				strings.HasSuffix(host, "."+strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/xcoulon/converse-mcp/pkg/server"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,3 +76,149 @@ func TestLoggingMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestOriginValidationMiddleware(t *testing.T) {
+	// given
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := server.OriginValidationMiddleware([]string{"https://example.com", "https://*.example.org"}, next)
+
+	testCases := []struct {
+		name           string
+		origin         string
+		expectedStatus int
+	}{
+		{
+			name:           "no origin",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "exact origin",
+			origin:         "https://example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wildcard subdomain",
+			origin:         "https://inspector.example.org",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wildcard does not match parent domain",
+			origin:         "https://example.org",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "other scheme",
+			origin:         "http://example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "other origin",
+			origin:         "https://evil.com",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if testCase.origin != "" {
+				req.Header.Set("Origin", testCase.origin)
+			}
+			rec := httptest.NewRecorder()
+
+			// when
+			h.ServeHTTP(rec, req)
+
+			// then
+			require.Equal(t, testCase.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).Build()
+	h := server.NewHTTPHandler(router, logger,
+		server.WithAllowedOrigins("http://localhost:6274"),
+		server.WithCORS(server.CORSOptions{MaxAge: time.Hour}),
+	)
+
+	t.Run("preflight from allowed origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/mcp", nil)
+		req.Header.Set("Origin", "http://localhost:6274")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,mcp-session-id")
+		rec := httptest.NewRecorder()
+
+		// when
+		h.ServeHTTP(rec, req)
+
+		// then
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "http://localhost:6274", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST, DELETE, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Mcp-Session-Id")
+		assert.Equal(t, "3600", rec.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("preflight from other origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/mcp", nil)
+		req.Header.Set("Origin", "https://evil.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+
+		// when
+		h.ServeHTTP(rec, req)
+
+		// then
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight with method not allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/mcp", nil)
+		req.Header.Set("Origin", "http://localhost:6274")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		rec := httptest.NewRecorder()
+
+		// when
+		h.ServeHTTP(rec, req)
+
+		// then
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("initialize from allowed origin exposes session id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		req.Header.Set("Origin", "http://localhost:6274")
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		// when
+		h.ServeHTTP(rec, req)
+
+		// then
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "http://localhost:6274", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Mcp-Session-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+		assert.NotEmpty(t, rec.Header().Get(server.SessionIDHeader))
+	})
+
+	t.Run("request from other origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		req.Header.Set("Origin", "https://evil.com")
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		// when
+		h.ServeHTTP(rec, req)
+
+		// then
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rec.Header().Get(server.SessionIDHeader))
+	})
+}
@@ -2,16 +2,18 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/handler"
 	api "github.com/xcoulon/converse-mcp/pkg/api"
 )
 
-const protocolVersion = "2025-06-18"
-
 type PromptHandleFunc func(ctx context.Context, params api.GetPromptRequestParams) (api.GetPromptResult, error)
 
 type PromptHandler struct {
@@ -26,11 +28,37 @@ type ResourceHandler struct {
 	Handle   ResourceHandleFunc
 }
 
+// ToolHandleFunc handles a tool call. Errors are reported in a result with the `isError` flag set, so that the LLM
+// can see them, except for `*jrpc2.Error` values which are returned as protocol errors (see `ToolResultFromError`).
 type ToolHandleFunc func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error)
 
 type ToolHandler struct {
 	Tool   api.Tool
 	Handle ToolHandleFunc
+	// RateLimit overrides the default per-tool limit configured with `RouterBuilder.WithRateLimits`
+	RateLimit *RateLimit
+	// SkipInputValidation disables the validation of the arguments against the input schema of the tool
+	SkipInputValidation bool
+	// Timeout overrides the default timeout configured with `RouterBuilder.WithDefaultToolTimeout`
+	Timeout *time.Duration
+}
+
+// ToolOption configures the registration of a tool
+type ToolOption func(*ToolHandler)
+
+// WithToolRateLimit sets the rate limit and concurrency limit of the tool
+func WithToolRateLimit(limit RateLimit) ToolOption {
+	return func(h *ToolHandler) {
+		h.RateLimit = &limit
+	}
+}
+
+// WithoutInputValidation disables the validation of the arguments against the input schema of the tool,
+// for example when the handler performs its own validation
+func WithoutInputValidation() ToolOption {
+	return func(h *ToolHandler) {
+		h.SkipInputValidation = true
+	}
 }
 
 type Router handler.Map
@@ -39,8 +67,25 @@ type RouterBuilder struct {
 	serverInfo   api.Implementation
 	prompts      []PromptHandler
 	resources    []ResourceHandler
-	tools        []ToolHandler
-	logger       *slog.Logger
+	// routes of the resources which are not listed
+	resourceRoutes []ResourceRoute
+	// providers of the resources which are listed in addition to the registered ones
+	resourceProviders []ResourceProvider
+	tools             []ToolHandler
+	limits            RateLimits
+	validation        OutputValidation
+	toolTimeout       time.Duration
+	logger            *slog.Logger
+	// errors found during the registrations, reported by `Validate`
+	errs []error
+	// global middlewares
+	middlewares         []Middleware
+	toolMiddlewares     []ToolMiddleware
+	promptMiddlewares   []PromptMiddleware
+	resourceMiddlewares []ResourceMiddleware
+	// maximum number of items in the responses to the list requests (0 for no pagination)
+	pageSize      int
+	subscriptions *Subscriptions
 }
 
 func NewRouterBuilder(name, version string, logger *slog.Logger) *RouterBuilder {
@@ -61,78 +106,191 @@ func NewRouterBuilder(name, version string, logger *slog.Logger) *RouterBuilder
 			Name:    name,
 			Version: version,
 		},
-		prompts:   []PromptHandler{},
-		resources: []ResourceHandler{},
-		tools:     []ToolHandler{},
-		logger:    logger,
+		prompts:     []PromptHandler{},
+		resources:   []ResourceHandler{},
+		tools:       []ToolHandler{},
+		toolTimeout: DefaultToolTimeout,
+		logger:      logger,
 	}
 }
 
-func (b *RouterBuilder) WithPrompt(prompt api.Prompt, handle PromptHandleFunc) *RouterBuilder {
+func (b *RouterBuilder) WithPrompt(prompt api.Prompt, handle PromptHandleFunc, opts ...PromptOption) *RouterBuilder {
 	b.logger.Debug("with prompt", "prompt", prompt.Name)
-	b.prompts = append(b.prompts, PromptHandler{
+	h := PromptHandler{
 		Prompt: prompt,
 		Handle: handle,
-	})
+	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	b.prompts = append(b.prompts, h)
 	// Servers that support prompts MUST declare the prompts capability
 	b.capabilities.Prompts.ListChanged = api.BoolPtr(true)
 	return b
 }
 
-func (b *RouterBuilder) WithResource(resource api.Resource, handle ResourceHandleFunc) *RouterBuilder {
+func (b *RouterBuilder) WithResource(resource api.Resource, handle ResourceHandleFunc, opts ...ResourceOption) *RouterBuilder {
 	b.logger.Debug("with resource", "resource", resource.Name)
-	b.resources = append(b.resources, ResourceHandler{
+	h := ResourceHandler{
 		Resource: resource,
 		Handle:   handle,
-	})
+	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	b.resources = append(b.resources, h)
 	// Servers that support resources MUST declare the resources capability
 	b.capabilities.Resources.ListChanged = api.BoolPtr(true)
 	return b
 }
 
-func (b *RouterBuilder) WithTool(tool api.Tool, handle ToolHandleFunc) *RouterBuilder {
+func (b *RouterBuilder) WithTool(tool api.Tool, handle ToolHandleFunc, opts ...ToolOption) *RouterBuilder {
 	b.logger.Debug("with tool", "tool", tool.Name)
-	b.tools = append(b.tools, ToolHandler{
+	h := ToolHandler{
 		Tool:   tool,
 		Handle: handle,
-	})
+	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	b.tools = append(b.tools, h)
 	// Servers that support tools MUST declare the tools capability
 	b.capabilities.Tools.ListChanged = api.BoolPtr(true)
 	return b
 }
 
+// WithRateLimits configures the global, per-session and default per-tool limits applied on tool calls
+func (b *RouterBuilder) WithRateLimits(limits RateLimits) *RouterBuilder {
+	b.limits = limits
+	return b
+}
+
+// OutputValidation defines how the results of the tools which declare an output schema are validated
+type OutputValidation int
+
+const (
+	// OutputValidationOff disables the validation of the tool results
+	OutputValidationOff OutputValidation = iota
+	// OutputValidationWarn logs a warning when a tool result does not conform to the output schema of the tool
+	OutputValidationWarn
+	// OutputValidationStrict replaces the tool results which do not conform to the output schema of the tool
+	// with an internal error
+	OutputValidationStrict
+)
+
+// WithOutputValidation configures the validation of the structured content of the tool results against the
+// output schema of the tools (disabled by default). Results with the `isError` flag set are not validated.
+func (b *RouterBuilder) WithOutputValidation(validation OutputValidation) *RouterBuilder {
+	b.validation = validation
+	return b
+}
+
+// Build returns the router. It panics if several resources have the same URI or the same prefix.
+// The other registrations are not validated: use `Validate` or `MustBuild` to check them.
 func (b *RouterBuilder) Build() Router {
-	return Router(handler.Map{
-		"initialize":     initialize(b.capabilities, b.serverInfo, b.logger),
-		"prompts/list":   listPrompts(b.prompts, b.logger),
-		"prompts/get":    getPrompt(b.prompts, b.logger),
-		"resources/list": listResources(b.resources, b.logger),
-		"resources/read": readResource(b.resources, b.logger),
-		"tools/list":     listTools(b.tools, b.logger),
-		"tools/call":     callTool(b.tools, b.logger),
+	prompts, resources, resourceRoutes, tools := b.withMiddlewares()
+	resourceRouter, err := newResourceRouter(resources, resourceRoutes)
+	if err != nil {
+		panic(err.Error())
+	}
+	router := Router(handler.Map{
+		"initialize":              initialize(b.capabilities, b.serverInfo, b.logger),
+		"notifications/cancelled": cancelRequest(b.logger),
+		"prompts/list":            listPrompts(prompts, b.pageSize, b.logger),
+		"prompts/get":             getPrompt(prompts, b.logger),
+		"resources/list":          listResources(resources, b.resourceProviders, b.pageSize, b.logger),
+		"resources/read":          readResource(resourceRouter, b.logger),
+		"tools/list":              listTools(tools, b.pageSize, b.logger),
+		"tools/call":              callTool(tools, newLimiter(b.limits, tools), b.validation, b.toolTimeout, b.logger),
 	})
+	if b.subscriptions != nil {
+		router["initialize"] = b.subscriptions.withSessionTracking(router["initialize"])
+		router["resources/subscribe"] = b.subscriptions.subscribe(resourceRouter)
+		router["resources/unsubscribe"] = b.subscriptions.unsubscribe
+	}
+	for method, h := range router {
+		router[method] = withRecovery(withProgressToken(withResultConversion(chain(h, b.middlewares))), b.logger)
+	}
+	return router
+}
+
+// MustBuild validates the registrations (see `Validate`) and returns the router. It panics if they are invalid.
+func (b *RouterBuilder) MustBuild() Router {
+	if err := b.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid router:\n%v", err))
+	}
+	return b.Build()
 }
 
 func initialize(capabilities api.ServerCapabilities, serverInfo api.Implementation, logger *slog.Logger) jrpc2.Handler {
-	return func(_ context.Context, _ *jrpc2.Request) (any, error) {
-		logger.Debug("initialize")
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		params := api.InitializeRequestParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
+		version := api.NegotiateProtocolVersion(params.ProtocolVersion)
+		logger.Debug("initialize", "requested_version", params.ProtocolVersion, "version", version)
+		if s := sessionFromContext(ctx); s != nil {
+			s.setProtocolVersion(version)
+			s.setClientCapabilities(params.Capabilities)
+		}
 		return &api.InitializeResult{
-			ProtocolVersion: protocolVersion,
+			ProtocolVersion: version,
 			ServerInfo:      serverInfo,
 			Capabilities:    capabilities,
 		}, nil
 	}
 }
 
-func listPrompts(handlers []PromptHandler, logger *slog.Logger) jrpc2.Handler {
+// withResultConversion converts the results for the clients which use an older protocol version
+func withResultConversion(h jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		result, err := h(ctx, req)
+		if err != nil {
+			return result, err
+		}
+		return api.ConvertResult(result, ProtocolVersionFromContext(ctx)), nil
+	}
+}
+
+// cancelRequest cancels the in-flight request whose ID is given in the `notifications/cancelled` notification
+func cancelRequest(logger *slog.Logger) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		params := api.CancelledNotificationParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
+		logger.Debug("cancel request", "id", params.RequestId.String())
+		// the server identifies the requests with the JSON encoding of their ID
+		id, err := json.Marshal(params.RequestId)
+		if err != nil {
+			return nil, err
+		}
+		if srv := jrpc2.ServerFromContext(ctx); srv != nil {
+			srv.CancelRequest(string(id))
+		}
+		return nil, nil
+	}
+}
+
+func listPrompts(handlers []PromptHandler, pageSize int, logger *slog.Logger) jrpc2.Handler {
 	prompts := make([]api.Prompt, 0, len(handlers))
 	for _, h := range handlers {
 		prompts = append(prompts, h.Prompt)
 	}
-	return func(_ context.Context, _ *jrpc2.Request) (any, error) {
+	return func(_ context.Context, req *jrpc2.Request) (any, error) {
+		params := api.ListPromptsRequestParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
 		logger.Debug("list prompts")
+		page, next, err := paginate(prompts, params.Cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
 		return &api.ListPromptsResult{
-			Prompts: prompts,
+			Prompts:    page,
+			NextCursor: next,
 		}, nil
 	}
 }
@@ -145,74 +303,171 @@ func getPrompt(handlers []PromptHandler, logger *slog.Logger) jrpc2.Handler {
 	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
 		params := api.GetPromptRequestParams{}
 		if err := req.UnmarshalParams(&params); err != nil {
-			return nil, fmt.Errorf("error while unmarshalling '%s' request parameters: %w", req.Method(), err)
+			return nil, invalidParamsError(req, err)
 		}
 		logger.Debug("get prompt", "name", params.Name)
 		if h, ok := prompts[params.Name]; ok {
+			if err := checkPromptArguments(h.Prompt, params.Arguments); err != nil {
+				return nil, err
+			}
 			return h.Handle(ctx, params)
 		}
-		return nil, fmt.Errorf("prompt '%s' does not exist", params.Name)
+		return nil, PromptNotFoundError(params.Name)
 	}
 }
 
-func listResources(handlers []ResourceHandler, logger *slog.Logger) jrpc2.Handler {
-	resources := make([]api.Resource, 0, len(handlers))
+func listResources(handlers []ResourceHandler, providers []ResourceProvider, pageSize int, logger *slog.Logger) jrpc2.Handler {
+	registered := make([]api.Resource, 0, len(handlers))
 	for _, h := range handlers {
-		resources = append(resources, h.Resource)
+		registered = append(registered, h.Resource)
 	}
-	return func(_ context.Context, _ *jrpc2.Request) (any, error) {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		params := api.ListResourcesRequestParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
 		logger.Debug("list resources")
+		resources := registered
+		if len(providers) > 0 {
+			resources = slices.Clone(registered)
+			for _, p := range providers {
+				provided, err := p.ListResources(ctx)
+				if err != nil {
+					logger.Error("failed to list the provided resources", "prefix", p.Prefix(), "error", err)
+					return nil, jrpc2.Errorf(jrpc2.InternalError, "failed to list the resources of '%s'", p.Prefix())
+				}
+				resources = append(resources, provided...)
+			}
+		}
+		page, next, err := paginate(resources, params.Cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
 		return &api.ListResourcesResult{
-			Resources: resources,
+			Resources:  page,
+			NextCursor: next,
 		}, nil
 	}
 }
 
-func readResource(handlers []ResourceHandler, logger *slog.Logger) jrpc2.Handler {
-	resources := make(map[string]ResourceHandler, len(handlers))
-	for _, h := range handlers {
-		resources[h.Resource.Name] = h
-	}
+func readResource(router *resourceRouter, logger *slog.Logger) jrpc2.Handler {
 	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
 		params := api.ReadResourceRequestParams{}
 		if err := req.UnmarshalParams(&params); err != nil {
-			return nil, fmt.Errorf("error while unmarshalling '%s' request parameters: %w", req.Method(), err)
+			return nil, invalidParamsError(req, err)
 		}
 		logger.Debug("read resource", "uri", params.Uri)
-		if h, ok := resources[params.Uri]; ok {
-			return h.Handle(ctx, params)
+		if handle, ok := router.lookup(params.Uri); ok {
+			return handle(ctx, params)
 		}
-		return nil, fmt.Errorf("resource '%s' does not exist", params.Uri)
+		return nil, ResourceNotFoundError(params.Uri)
 	}
 }
 
-func listTools(handlers []ToolHandler, logger *slog.Logger) jrpc2.Handler {
+func listTools(handlers []ToolHandler, pageSize int, logger *slog.Logger) jrpc2.Handler {
 	tools := make([]api.Tool, 0, len(handlers))
 	for _, h := range handlers {
 		tools = append(tools, h.Tool)
 	}
-	return func(_ context.Context, _ *jrpc2.Request) (any, error) {
+	return func(_ context.Context, req *jrpc2.Request) (any, error) {
+		params := api.ListToolsRequestParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
 		logger.Debug("list tools")
+		page, next, err := paginate(tools, params.Cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
 		return &api.ListToolsResult{
-			Tools: tools,
+			Tools:      page,
+			NextCursor: next,
 		}, nil
 	}
 }
 
-func callTool(handlers []ToolHandler, logger *slog.Logger) jrpc2.Handler {
+func callTool(handlers []ToolHandler, limiter *limiter, validation OutputValidation, timeout time.Duration, logger *slog.Logger) jrpc2.Handler {
 	tools := make(map[string]ToolHandler, len(handlers))
+	inputSchemas := make(map[string]*api.Schema, len(handlers))
+	outputSchemas := make(map[string]*api.Schema, len(handlers))
+	// invalid schemas are also reported by `RouterBuilder.Validate`
+	schemaErrs := make(map[string]error)
 	for _, h := range handlers {
 		tools[h.Tool.Name] = h
+		if !h.SkipInputValidation {
+			if schema, err := api.CompileSchema(h.Tool.InputSchema); err != nil {
+				logger.Error("invalid input schema", "tool", h.Tool.Name, "error", err)
+				schemaErrs[h.Tool.Name] = fmt.Errorf("invalid input schema of tool '%s': %w", h.Tool.Name, err)
+			} else {
+				inputSchemas[h.Tool.Name] = schema
+			}
+		}
+		if validation != OutputValidationOff && h.Tool.OutputSchema != nil {
+			if schema, err := api.CompileSchema(h.Tool.OutputSchema); err != nil {
+				logger.Error("invalid output schema", "tool", h.Tool.Name, "error", err)
+				schemaErrs[h.Tool.Name] = fmt.Errorf("invalid output schema of tool '%s': %w", h.Tool.Name, err)
+			} else {
+				outputSchemas[h.Tool.Name] = schema
+			}
+		}
 	}
 	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
 		params := api.CallToolRequestParams{}
 		if err := req.UnmarshalParams(&params); err != nil {
-			return nil, fmt.Errorf("error while unmarshalling '%s' request parameters: %w", req.Method(), err)
+			return nil, invalidParamsError(req, err)
 		}
 		logger.Debug("call tool", "name", params.Name)
 		if h, ok := tools[params.Name]; ok {
-			return h.Handle(ctx, params)
+			if err := schemaErrs[params.Name]; err != nil {
+				return nil, err
+			}
+			if schema, ok := inputSchemas[params.Name]; ok {
+				args := params.Arguments
+				if args == nil {
+					args = map[string]any{}
+				}
+				if err := schema.Validate(args); err != nil {
+					logger.Debug("invalid tool arguments", "name", params.Name, "error", err)
+					return newToolErrorResult(fmt.Sprintf("invalid arguments for tool '%s': %v", params.Name, err)), nil
+				}
+			}
+			release, err := limiter.acquire(SessionIDFromContext(ctx), params.Name)
+			if err != nil {
+				logger.Warn("call tool rejected", "name", params.Name, "error", err)
+				return nil, err
+			}
+			toolTimeout := timeout
+			if h.Timeout != nil {
+				toolTimeout = *h.Timeout
+			}
+			result, err := runTool(ctx, h, params, toolTimeout, release, logger)
+			if err != nil {
+				logger.Debug("call tool failed", "name", params.Name, "error", err)
+				return ToolResultFromError(err)
+			}
+			if schema, ok := outputSchemas[params.Name]; ok {
+				if err := ValidateToolResult(schema, result); err != nil {
+					if validation == OutputValidationStrict {
+						logger.Error("invalid tool result", "name", params.Name, "error", err)
+						return nil, jrpc2.Errorf(jrpc2.InternalError, "invalid result of tool '%s': %v", params.Name, err)
+					}
+					logger.Warn("invalid tool result", "name", params.Name, "error", err)
+				}
+			}
+			return result, nil
 		}
-		return nil, fmt.Errorf("tool '%s' does not exist", params.Name)
+		return nil, ToolNotFoundError(params.Name)
+	}
+}
+
+// ValidateToolResult checks that the structured content of the given result conforms to the given output schema.
+// Results with the `isError` flag set are not validated.
+func ValidateToolResult(schema *api.Schema, result api.CallToolResult) error {
+	if result.IsError != nil && *result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return errors.New("missing structured content")
 	}
+	return schema.Validate(result.StructuredContent)
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"slices"
+
+	"github.com/creachadair/jrpc2"
+)
+
+// Middleware intercepts the requests of any method (the method is given by `req.Method()`)
+type Middleware func(next jrpc2.Handler) jrpc2.Handler
+
+// ToolMiddleware intercepts the tool calls, after the validation of the arguments and the rate limiting
+type ToolMiddleware func(next ToolHandleFunc) ToolHandleFunc
+
+// PromptMiddleware intercepts the `prompts/get` requests
+type PromptMiddleware func(next PromptHandleFunc) PromptHandleFunc
+
+// ResourceMiddleware intercepts the `resources/read` requests
+type ResourceMiddleware func(next ResourceHandleFunc) ResourceHandleFunc
+
+// PromptOption configures the registration of a prompt
+type PromptOption func(*PromptHandler)
+
+// ResourceOption configures the registration of a resource
+type ResourceOption func(*ResourceHandler)
+
+// Use adds middlewares which intercept the requests of all methods.
+// Middlewares are applied in the order in which they are added, the first one being the outermost.
+func (b *RouterBuilder) Use(middlewares ...Middleware) *RouterBuilder {
+	b.middlewares = append(b.middlewares, middlewares...)
+	return b
+}
+
+// UseTool adds middlewares which intercept the calls of all tools, around the middlewares of each tool
+// (see `WithToolMiddleware`)
+func (b *RouterBuilder) UseTool(middlewares ...ToolMiddleware) *RouterBuilder {
+	b.toolMiddlewares = append(b.toolMiddlewares, middlewares...)
+	return b
+}
+
+// UsePrompt adds middlewares which intercept the requests of all prompts, around the middlewares of each prompt
+// (see `WithPromptMiddleware`)
+func (b *RouterBuilder) UsePrompt(middlewares ...PromptMiddleware) *RouterBuilder {
+	b.promptMiddlewares = append(b.promptMiddlewares, middlewares...)
+	return b
+}
+
+// UseResource adds middlewares which intercept the reads of all resources, around the middlewares of each resource
+// (see `WithResourceMiddleware`)
+func (b *RouterBuilder) UseResource(middlewares ...ResourceMiddleware) *RouterBuilder {
+	b.resourceMiddlewares = append(b.resourceMiddlewares, middlewares...)
+	return b
+}
+
+// WithToolMiddleware adds middlewares which only intercept the calls of the tool
+func WithToolMiddleware(middlewares ...ToolMiddleware) ToolOption {
+	return func(h *ToolHandler) {
+		h.Handle = chain(h.Handle, middlewares)
+	}
+}
+
+// WithPromptMiddleware adds middlewares which only intercept the requests of the prompt
+func WithPromptMiddleware(middlewares ...PromptMiddleware) PromptOption {
+	return func(h *PromptHandler) {
+		h.Handle = chain(h.Handle, middlewares)
+	}
+}
+
+// WithResourceMiddleware adds middlewares which only intercept the reads of the resource
+func WithResourceMiddleware(middlewares ...ResourceMiddleware) ResourceOption {
+	return func(h *ResourceHandler) {
+		h.Handle = chain(h.Handle, middlewares)
+	}
+}
+
+// chain wraps the given handler with the given middlewares, the first one being the outermost
+func chain[H any, M ~func(H) H](h H, middlewares []M) H {
+	for _, m := range slices.Backward(middlewares) {
+		h = m(h)
+	}
+	return h
+}
+
+// withMiddlewares returns copies of the handlers of the builder, wrapped with the global middlewares
+func (b *RouterBuilder) withMiddlewares() ([]PromptHandler, []ResourceHandler, []ResourceRoute, []ToolHandler) {
+	prompts := make([]PromptHandler, 0, len(b.prompts))
+	for _, h := range b.prompts {
+		h.Handle = chain(h.Handle, b.promptMiddlewares)
+		prompts = append(prompts, h)
+	}
+	resources := make([]ResourceHandler, 0, len(b.resources))
+	for _, h := range b.resources {
+		h.Handle = chain(h.Handle, b.resourceMiddlewares)
+		resources = append(resources, h)
+	}
+	resourceRoutes := make([]ResourceRoute, 0, len(b.resourceRoutes))
+	for _, r := range b.resourceRoutes {
+		r.Handle = chain(r.Handle, b.resourceMiddlewares)
+		resourceRoutes = append(resourceRoutes, r)
+	}
+	tools := make([]ToolHandler, 0, len(b.tools))
+	for _, h := range b.tools {
+		h.Handle = chain(h.Handle, b.toolMiddlewares)
+		tools = append(tools, h)
+	}
+	return prompts, resources, resourceRoutes, tools
+}
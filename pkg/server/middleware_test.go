@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewares(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	var mu sync.Mutex
+	calls := []string{}
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	reset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = []string{}
+	}
+	recorded := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, calls...)
+	}
+	toolMiddleware := func(name string) server.ToolMiddleware {
+		return func(next server.ToolHandleFunc) server.ToolHandleFunc {
+			return func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+				record(name + ":" + params.Name)
+				return next(ctx, params)
+			}
+		}
+	}
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		Use(func(next jrpc2.Handler) jrpc2.Handler {
+			return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+				record("method:" + req.Method())
+				return next(ctx, req)
+			}
+		}).
+		UseTool(toolMiddleware("first"), toolMiddleware("second")).
+		UsePrompt(func(next server.PromptHandleFunc) server.PromptHandleFunc {
+			return func(ctx context.Context, params api.GetPromptRequestParams) (api.GetPromptResult, error) {
+				record("prompt:" + params.Name)
+				return next(ctx, params)
+			}
+		}).
+		UseResource(func(_ server.ResourceHandleFunc) server.ResourceHandleFunc {
+			return func(_ context.Context, _ api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+				return api.ReadResourceResult{}, errors.New("access denied")
+			}
+		}).
+		WithTool(api.NewTool("my-tool"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			record("handle:my-tool")
+			return api.CallToolResult{Content: []api.CallToolResultContentElem{}}, nil
+		}, server.WithToolMiddleware(toolMiddleware("own"))).
+		WithTool(api.NewTool("other-tool"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			record("handle:other-tool")
+			return api.CallToolResult{Content: []api.CallToolResultContentElem{}}, nil
+		}).
+		WithPrompt(api.NewPrompt("my-prompt"), EmptyPromptHandle, server.WithPromptMiddleware(func(next server.PromptHandleFunc) server.PromptHandleFunc {
+			return func(ctx context.Context, params api.GetPromptRequestParams) (api.GetPromptResult, error) {
+				record("own:" + params.Name)
+				return next(ctx, params)
+			}
+		})).
+		WithResource(api.NewResource("my-resource", "file:///my-resource"), EmptyResourceHandle).
+		Build()
+	cl := mcptest.NewClient(t, router, mcptest.WithoutOutputValidation()) // no extra `tools/list` requests
+
+	t.Run("tool", func(t *testing.T) {
+		// given
+		reset()
+
+		// when
+		_, err := cl.CallTool(context.Background(), "my-tool", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"method:tools/call", "first:my-tool", "second:my-tool", "own:my-tool", "handle:my-tool"}, recorded())
+	})
+
+	t.Run("other tool", func(t *testing.T) {
+		// given
+		reset()
+
+		// when
+		_, err := cl.CallTool(context.Background(), "other-tool", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"method:tools/call", "first:other-tool", "second:other-tool", "handle:other-tool"}, recorded())
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		// given
+		reset()
+
+		// when
+		_, err := cl.GetPrompt(context.Background(), "my-prompt", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"method:prompts/get", "prompt:my-prompt", "own:my-prompt"}, recorded())
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		// when
+		_, err := cl.ReadResource(context.Background(), "file:///my-resource")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "access denied")
+	})
+}
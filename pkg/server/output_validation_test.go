@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputValidation(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	tool := api.NewTool("weather").WithOutputProperty("temperature", api.TypeNumber, "the temperature", true)
+	validHandle := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		return api.CallToolResult{
+			Content:           []api.CallToolResultContentElem{},
+			StructuredContent: map[string]any{"temperature": 21.5},
+		}, nil
+	}
+	invalidHandle := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		return api.CallToolResult{
+			Content:           []api.CallToolResultContentElem{},
+			StructuredContent: map[string]any{"temperature": "warm"},
+		}, nil
+	}
+	unstructuredHandle := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		return api.CallToolResult{
+			Content: []api.CallToolResultContentElem{},
+		}, nil
+	}
+	errorHandle := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		return api.CallToolResult{
+			Content: []api.CallToolResultContentElem{},
+			IsError: api.BoolPtr(true),
+		}, nil
+	}
+
+	testCases := []struct {
+		name          string
+		validation    server.OutputValidation
+		handle        server.ToolHandleFunc
+		expectedError string
+	}{
+		{
+			name:       "valid result",
+			validation: server.OutputValidationStrict,
+			handle:     validHandle,
+		},
+		{
+			name:          "invalid result",
+			validation:    server.OutputValidationStrict,
+			handle:        invalidHandle,
+			expectedError: "invalid result of tool 'weather': 'temperature' must be of type number, not string",
+		},
+		{
+			name:          "missing structured content",
+			validation:    server.OutputValidationStrict,
+			handle:        unstructuredHandle,
+			expectedError: "invalid result of tool 'weather': missing structured content",
+		},
+		{
+			name:       "error result",
+			validation: server.OutputValidationStrict,
+			handle:     errorHandle,
+		},
+		{
+			name:       "invalid result with warning",
+			validation: server.OutputValidationWarn,
+			handle:     invalidHandle,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(tool, testCase.handle).
+				WithOutputValidation(testCase.validation).
+				Build()
+			cl := mcptest.NewClient(t, router, mcptest.WithoutOutputValidation())
+
+			// when
+			_, err := cl.CallTool(context.Background(), "weather", nil)
+
+			// then
+			if testCase.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			var rpcErr *jrpc2.Error
+			require.ErrorAs(t, err, &rpcErr)
+			assert.Equal(t, jrpc2.InternalError, rpcErr.Code)
+			assert.Equal(t, testCase.expectedError, rpcErr.Message)
+		})
+	}
+
+	t.Run("no output schema", func(t *testing.T) {
+		// given
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithTool(api.NewTool("my-tool"), unstructuredHandle).
+			WithOutputValidation(server.OutputValidationStrict).
+			Build()
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		tools, err1 := cl.ListTools(context.Background())
+		_, err2 := cl.CallTool(context.Background(), "my-tool", nil)
+
+		// then
+		require.NoError(t, err1)
+		assert.Nil(t, tools.Tools[0].OutputSchema)
+		require.NoError(t, err2)
+	})
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// WithPageSize sets the maximum number of prompts, resources and tools returned in the responses to the
+// `prompts/list`, `resources/list` and `tools/list` requests. The clients retrieve the next pages with the cursor
+// of the response. The lists are not paginated by default.
+func (b *RouterBuilder) WithPageSize(size int) *RouterBuilder {
+	b.pageSize = size
+	return b
+}
+
+// paginate returns the page of items which starts at the given cursor, and the cursor of the next page
+// (nil on the last page). The cursor is the encoded offset of the first item of the page.
+func paginate[T any](items []T, cursor *string, pageSize int) ([]T, *string, error) {
+	start := 0
+	if cursor != nil {
+		offset, err := decodeCursor(*cursor)
+		if err != nil || offset > len(items) {
+			return nil, nil, jrpc2.Errorf(jrpc2.InvalidParams, "invalid cursor '%s'", *cursor)
+		}
+		start = offset
+	}
+	if pageSize <= 0 || start+pageSize >= len(items) {
+		return items[start:], nil, nil
+	}
+	next := api.EncodeBase64([]byte(strconv.Itoa(start + pageSize)))
+	return items[start : start+pageSize], &next, nil
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := api.DecodeBase64(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid offset")
+	}
+	return offset, nil
+}
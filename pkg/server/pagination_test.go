@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagination(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	b := server.NewRouterBuilder("converse-mcp", "0.1", logger).WithPageSize(2)
+	for i := range 5 {
+		b.WithResource(api.NewResource(fmt.Sprintf("r%d", i), fmt.Sprintf("text:///r%d", i)), TextResourceHandle("hello"))
+	}
+	cl := mcptest.NewClient(t, b.Build())
+
+	t.Run("list all pages", func(t *testing.T) {
+		// when
+		names := []string{}
+		var cursor *string
+		pages := 0
+		for {
+			result := api.ListResourcesResult{}
+			err := cl.CallResult(context.Background(), "resources/list", api.ListResourcesRequestParams{
+				Cursor: cursor,
+			}, &result)
+			require.NoError(t, err)
+			pages++
+			for _, r := range result.Resources {
+				names = append(names, r.Name)
+			}
+			if result.NextCursor == nil {
+				break
+			}
+			cursor = result.NextCursor
+		}
+
+		// then
+		assert.Equal(t, 3, pages)
+		assert.Equal(t, []string{"r0", "r1", "r2", "r3", "r4"}, names)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		// when
+		err := cl.CallResult(context.Background(), "resources/list", api.ListResourcesRequestParams{
+			Cursor: api.StringPtr("invalid"),
+		}, &api.ListResourcesResult{})
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, jrpc2.InvalidParams, rpcErr.Code)
+		assert.Equal(t, "invalid cursor 'invalid'", rpcErr.Message)
+	})
+
+	t.Run("no pagination by default", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger)
+		for i := range 3 {
+			b.WithTool(api.NewTool(fmt.Sprintf("t%d", i)), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				return api.CallToolResult{}, nil
+			})
+		}
+		cl := mcptest.NewClient(t, b.Build())
+
+		// when
+		result, err := cl.ListTools(context.Background())
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, result.Tools, 3)
+		assert.Nil(t, result.NextCursor)
+	})
+}
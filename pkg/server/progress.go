@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+type progressTokenKey struct{}
+
+// ContextWithProgressToken returns a copy of the given context with the given progress token
+func ContextWithProgressToken(ctx context.Context, token api.ProgressToken) context.Context {
+	return context.WithValue(ctx, progressTokenKey{}, token)
+}
+
+// ProgressTokenFromContext returns the progress token sent by the client in the `_meta` of the request
+// being handled, and `false` if the client did not ask for progress notifications
+func ProgressTokenFromContext(ctx context.Context) (api.ProgressToken, bool) {
+	token, ok := ctx.Value(progressTokenKey{}).(api.ProgressToken)
+	return token, ok
+}
+
+// NotifyProgress sends a progress notification for the request being handled, if the client asked for it
+// with a progress token (it does nothing otherwise). The total and message are optional.
+func NotifyProgress(ctx context.Context, progress float64, total *float64, message string) error {
+	token, ok := ProgressTokenFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	srv := jrpc2.ServerFromContext(ctx)
+	if srv == nil {
+		return errors.New("no server in context")
+	}
+	params := api.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+	}
+	if message != "" {
+		params.Message = &message
+	}
+	return srv.Notify(ctx, "notifications/progress", params)
+}
+
+// withProgressToken adds the progress token found in the `_meta` of the request params (if any) to the context
+func withProgressToken(h jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		if req.HasParams() {
+			params := struct {
+				Meta *struct {
+					ProgressToken *api.ProgressToken `json:"progressToken"`
+				} `json:"_meta"`
+			}{}
+			if err := json.Unmarshal([]byte(req.ParamString()), &params); err == nil && params.Meta != nil && params.Meta.ProgressToken != nil {
+				ctx = ContextWithProgressToken(ctx, *params.Meta.ProgressToken)
+			}
+		}
+		return h(ctx, req)
+	}
+}
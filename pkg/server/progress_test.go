@@ -0,0 +1,136 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	b := server.NewRouterBuilder("converse-mcp", "0.1", logger)
+	b.WithTool(api.NewTool("count"), func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		total := 2.0
+		for i := range 2 {
+			if err := server.NotifyProgress(ctx, float64(i+1), &total, "counting"); err != nil {
+				return api.CallToolResult{}, err
+			}
+		}
+		return api.CallToolResult{
+			Content: []api.CallToolResultContentElem{api.Text("done")},
+		}, nil
+	})
+	router := b.Build()
+
+	t.Run("with progress token", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		_, err := cl.Call(context.Background(), "tools/call", map[string]any{
+			"name":  "count",
+			"_meta": map[string]any{"progressToken": "abc"},
+		})
+
+		// then
+		require.NoError(t, err)
+		total := 2.0
+		message := "counting"
+		// notifications are not necessarily received in the order in which they were sent
+		progress := func() []api.ProgressNotificationParams {
+			result := []api.ProgressNotificationParams{}
+			for _, n := range cl.Notifications() {
+				if n.Method == "notifications/progress" {
+					params := api.ProgressNotificationParams{}
+					require.NoError(t, n.UnmarshalParams(&params))
+					result = append(result, params)
+				}
+			}
+			return result
+		}
+		require.Eventually(t, func() bool { return len(progress()) == 2 }, time.Second, 10*time.Millisecond)
+		assert.ElementsMatch(t, []api.ProgressNotificationParams{
+			{ProgressToken: api.StringProgressToken("abc"), Progress: 1, Total: &total, Message: &message},
+			{ProgressToken: api.StringProgressToken("abc"), Progress: 2, Total: &total, Message: &message},
+		}, progress())
+	})
+
+	t.Run("over HTTP", func(t *testing.T) {
+		// given
+		session := newHTTPTestSession(t, router, `{}`)
+		session.openEventStream()
+
+		// when
+		resp, _, err := session.post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"count","_meta":{"progressToken":"abc"}}}`)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		for range 2 {
+			n := session.receive()
+			assert.Equal(t, "notifications/progress", n.Method)
+			params := api.ProgressNotificationParams{}
+			require.NoError(t, json.Unmarshal(n.Params, &params))
+			assert.Equal(t, api.StringProgressToken("abc"), params.ProgressToken)
+		}
+	})
+
+	t.Run("without progress token", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		result, err := cl.CallTool(context.Background(), "count", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "done")
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = cl.WaitForNotification(ctx, "notifications/progress")
+		assert.Error(t, err)
+	})
+}
+
+func TestCancellation(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	b := server.NewRouterBuilder("converse-mcp", "0.1", logger)
+	cancelled := make(chan error, 1)
+	b.WithTool(api.NewTool("wait"), func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return api.CallToolResult{}, ctx.Err()
+	})
+	cl := mcptest.NewClient(t, b.Build(), mcptest.WithConcurrency(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	// when
+	_, err := cl.CallTool(ctx, "wait", nil)
+
+	// then
+	require.ErrorIs(t, err, context.Canceled)
+	select {
+	case err := <-cancelled:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the request was not cancelled on the server side")
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// PromptMessageTemplate is a message of a templated prompt (see `TemplatePromptHandle`)
+type PromptMessageTemplate struct {
+	Role api.Role
+	// Text is a `text/template` rendered with the arguments of the prompt (eg: `Summarize {{.topic}}`).
+	// Missing arguments are rendered as empty strings.
+	Text string
+	// Content is the content block of the message when `Text` is empty (eg: `api.Image(...)` or
+	// `api.EmbeddedText(...)`). The text of an embedded text resource is rendered as a template too.
+	Content api.ContentBlock
+}
+
+// UserMessage returns a user message whose text is rendered from the given template
+func UserMessage(text string) PromptMessageTemplate {
+	return PromptMessageTemplate{Role: api.RoleUser, Text: text}
+}
+
+// AssistantMessage returns an assistant message whose text is rendered from the given template
+func AssistantMessage(text string) PromptMessageTemplate {
+	return PromptMessageTemplate{Role: api.RoleAssistant, Text: text}
+}
+
+// ContentMessage returns a message with the given content block (eg: an image or an embedded resource)
+func ContentMessage(role api.Role, content api.ContentBlock) PromptMessageTemplate {
+	return PromptMessageTemplate{Role: role, Content: content}
+}
+
+// TemplatePromptHandle returns a handle which renders the given messages with the arguments of the prompt.
+// It returns an error if a template cannot be parsed.
+func TemplatePromptHandle(description string, messages ...PromptMessageTemplate) (PromptHandleFunc, error) {
+	type compiled struct {
+		role     api.Role
+		text     *template.Template
+		content  api.ContentBlock
+		resource *api.EmbeddedResource
+	}
+	templates := make([]compiled, 0, len(messages))
+	for i, m := range messages {
+		c := compiled{role: m.Role}
+		var err error
+		switch content := m.Content.(type) {
+		case nil:
+			c.text, err = parseTemplate(i, m.Text)
+		case api.EmbeddedResource:
+			c.resource = &content
+			c.text, err = parseTemplate(i, content.Resource.Text)
+		default:
+			c.content = content
+		}
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, c)
+	}
+	var desc *string
+	if description != "" {
+		desc = &description
+	}
+	return func(_ context.Context, params api.GetPromptRequestParams) (api.GetPromptResult, error) {
+		args := params.Arguments
+		if args == nil {
+			args = map[string]string{}
+		}
+		result := api.GetPromptResult{
+			Description: desc,
+			Messages:    make([]api.PromptMessage, 0, len(templates)),
+		}
+		for i, t := range templates {
+			content := t.content
+			if t.text != nil {
+				text := &strings.Builder{}
+				if err := t.text.Execute(text, args); err != nil {
+					return api.GetPromptResult{}, fmt.Errorf("error while rendering message #%d of prompt '%s': %w", i+1, params.Name, err)
+				}
+				if t.resource != nil {
+					r := *t.resource
+					r.Resource.Text = text.String()
+					content = r
+				} else {
+					content = api.Text(text.String())
+				}
+			}
+			result.Messages = append(result.Messages, api.PromptMessage{
+				Role:    t.role,
+				Content: content,
+			})
+		}
+		return result, nil
+	}, nil
+}
+
+func parseTemplate(index int, text string) (*template.Template, error) {
+	t, err := template.New(fmt.Sprintf("message-%d", index+1)).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template of message #%d: %w", index+1, err)
+	}
+	return t, nil
+}
+
+// WithPromptTemplate adds a prompt whose messages are rendered from the given templates (see `TemplatePromptHandle`).
+// Invalid templates are reported by `Validate`.
+func (b *RouterBuilder) WithPromptTemplate(prompt api.Prompt, messages []PromptMessageTemplate, opts ...PromptOption) *RouterBuilder {
+	description := ""
+	if prompt.Description != nil {
+		description = *prompt.Description
+	}
+	handle, err := TemplatePromptHandle(description, messages...)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("prompt '%s': %w", prompt.Name, err))
+		handle = func(_ context.Context, _ api.GetPromptRequestParams) (api.GetPromptResult, error) {
+			return api.GetPromptResult{}, err
+		}
+	}
+	return b.WithPrompt(prompt, handle, opts...)
+}
+
+// checkPromptArguments returns an `invalid params` error if some required arguments of the prompt are missing
+func checkPromptArguments(prompt api.Prompt, args map[string]string) error {
+	missing := []string{}
+	for _, a := range prompt.Arguments {
+		if a.Required == nil || !*a.Required {
+			continue
+		}
+		if _, ok := args[a.Name]; !ok {
+			missing = append(missing, a.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return MissingPromptArgumentsError(prompt.Name, missing)
+	}
+	return nil
+}
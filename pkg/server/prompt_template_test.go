@@ -0,0 +1,87 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptTemplate(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithPromptTemplate(api.NewPrompt("review").
+			WithDescription("Review some code").
+			WithArgument("language", "Language", "the programming language", true).
+			WithArgument("focus", "Focus", "the focus of the review", false),
+			[]server.PromptMessageTemplate{
+				server.UserMessage("Review the following {{.language}} code{{with .focus}}, focusing on {{.}}{{end}}."),
+				server.ContentMessage(api.RoleUser, api.EmbeddedText("file:///main.go", "// {{.language}} code", "text/x-go")),
+				server.ContentMessage(api.RoleUser, api.Image([]byte("png"), "image/png")),
+				server.AssistantMessage("Sure, let me review it."),
+			}).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	t.Run("all arguments", func(t *testing.T) {
+		// when
+		result, err := cl.GetPrompt(context.Background(), "review", map[string]string{
+			"language": "Go",
+			"focus":    "error handling",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, api.GetPromptResult{
+			Description: api.StringPtr("Review some code"),
+			Messages: []api.PromptMessage{
+				{Role: api.RoleUser, Content: api.Text("Review the following Go code, focusing on error handling.")},
+				{Role: api.RoleUser, Content: api.EmbeddedText("file:///main.go", "// Go code", "text/x-go")},
+				{Role: api.RoleUser, Content: api.Image([]byte("png"), "image/png")},
+				{Role: api.RoleAssistant, Content: api.Text("Sure, let me review it.")},
+			},
+		}, result)
+	})
+
+	t.Run("missing optional argument", func(t *testing.T) {
+		// when
+		result, err := cl.GetPrompt(context.Background(), "review", map[string]string{
+			"language": "Go",
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, api.Text("Review the following Go code."), result.Messages[0].Content)
+	})
+
+	t.Run("missing required argument", func(t *testing.T) {
+		// when
+		_, err := cl.GetPrompt(context.Background(), "review", nil)
+
+		// then
+		assertRPCError(t, err, jrpc2.InvalidParams, "missing required arguments for prompt 'review': 'language'", `{"name":"review","missing":["language"]}`)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithPromptTemplate(api.NewPrompt("invalid"), []server.PromptMessageTemplate{
+				server.UserMessage("{{.topic"),
+			})
+
+		// when
+		err := b.Validate()
+
+		// then
+		require.ErrorContains(t, err, "prompt 'invalid': invalid template of message #1")
+	})
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+)
+
+// RateLimitedCode is the JSON-RPC error code returned when a call is rejected because a rate limit
+// or a concurrency limit was exceeded. The error data contains the `scope` of the limit (`global`, `session` or `tool`)
+// and the `limit` which was exceeded (`rate` or `inFlight`). When the rate limit was exceeded, the data also contains
+// the number of seconds after which the call can be retried (`retryAfter`). There is no such estimate when
+// the maximum number of calls in flight was reached, since it depends on the duration of the calls in progress.
+const RateLimitedCode jrpc2.Code = -32029
+
+// RateLimit configures a token bucket refilled at `Rate` calls per second, with a capacity of `Burst` calls,
+// along with a maximum number of calls in flight. A zero value disables the corresponding limit.
+type RateLimit struct {
+	Rate        float64
+	Burst       int
+	MaxInFlight int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 || l.MaxInFlight > 0
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst < 1 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+// RateLimits configures the limits applied on tool calls
+type RateLimits struct {
+	// Global is shared by all sessions and all tools
+	Global RateLimit
+	// PerSession is applied to each session independently.
+	// It is not applied on requests without a session ID (eg: stdio or HTTP requests without a `Mcp-Session-Id` header),
+	// since these would otherwise all share the same bucket.
+	PerSession RateLimit
+	// PerTool is applied to each tool which does not declare its own limit
+	PerTool RateLimit
+}
+
+// sweepInterval is the interval at which idle session buckets are discarded
+const sweepInterval = time.Minute
+
+type limiter struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	global     *bucket
+	perSession RateLimit
+	sessions   map[string]*bucket
+	tools      map[string]*bucket
+	lastSweep  time.Time
+}
+
+// newLimiter returns a limiter for the given limits and tools, or nil if no limit was configured at all
+func newLimiter(limits RateLimits, handlers []ToolHandler) *limiter {
+	now := time.Now()
+	l := &limiter{
+		now:        time.Now,
+		global:     newBucket(limits.Global, now),
+		perSession: limits.PerSession,
+		sessions:   map[string]*bucket{},
+		tools:      make(map[string]*bucket, len(handlers)),
+		lastSweep:  now,
+	}
+	enabled := limits.Global.enabled() || limits.PerSession.enabled()
+	for _, h := range handlers {
+		limit := limits.PerTool
+		if h.RateLimit != nil {
+			limit = *h.RateLimit
+		}
+		if b := newBucket(limit, now); b != nil {
+			l.tools[h.Tool.Name] = b
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	return l
+}
+
+// acquire admits a call to the given tool in the given session, or returns a `RateLimitedCode` error
+// if any of the applicable limits is exceeded. The returned func must be called when the call completes.
+func (l *limiter) acquire(sessionID, toolName string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	var session *bucket
+	if sessionID != "" {
+		var ok bool
+		if session, ok = l.sessions[sessionID]; !ok {
+			session = newBucket(l.perSession, now)
+			if session != nil {
+				l.sessions[sessionID] = session
+			}
+		}
+	}
+	scopes := []struct {
+		name   string
+		bucket *bucket
+	}{
+		{name: "global", bucket: l.global},
+		{name: "session", bucket: session},
+		{name: "tool", bucket: l.tools[toolName]},
+	}
+	// check all the limits before consuming any token, so that a rejected call has no side effect
+	for _, s := range scopes {
+		switch retryAfter, ok := s.bucket.admit(now); {
+		case ok:
+			continue
+		case retryAfter == 0:
+			return nil, jrpc2.Errorf(RateLimitedCode, "too many calls to tool '%s' in flight: %s limit exceeded", toolName, s.name).
+				WithData(map[string]any{
+					"scope": s.name,
+					"limit": "inFlight",
+				})
+		default:
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			return nil, jrpc2.Errorf(RateLimitedCode, "too many calls to tool '%s': %s limit exceeded, retry after %ds", toolName, s.name, seconds).
+				WithData(map[string]any{
+					"scope":      s.name,
+					"limit":      "rate",
+					"retryAfter": seconds,
+				})
+		}
+	}
+	for _, s := range scopes {
+		s.bucket.take()
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, s := range scopes {
+			s.bucket.release()
+		}
+	}, nil
+}
+
+// sweep discards the session buckets which are back to their initial state
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for id, b := range l.sessions {
+		if b.idle(now) {
+			delete(l.sessions, id)
+		}
+	}
+}
+
+type bucket struct {
+	limit    RateLimit
+	tokens   float64
+	last     time.Time
+	inFlight int
+}
+
+// newBucket returns a full bucket for the given limit, or nil if the limit is disabled
+func newBucket(limit RateLimit, now time.Time) *bucket {
+	if !limit.enabled() {
+		return nil
+	}
+	return &bucket{
+		limit:  limit,
+		tokens: limit.burst(),
+		last:   now,
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.limit.Rate <= 0 {
+		return
+	}
+	b.tokens = math.Min(b.limit.burst(), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// admit returns true if a call can be admitted, otherwise the duration after which the call should be retried
+// (or 0 if the maximum number of calls in flight was reached)
+func (b *bucket) admit(now time.Time) (time.Duration, bool) {
+	if b == nil {
+		return 0, true
+	}
+	if b.limit.MaxInFlight > 0 && b.inFlight >= b.limit.MaxInFlight {
+		return 0, false
+	}
+	if b.limit.Rate > 0 {
+		b.refill(now)
+		if b.tokens < 1 {
+			return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second)), false
+		}
+	}
+	return 0, true
+}
+
+func (b *bucket) take() {
+	if b == nil {
+		return
+	}
+	if b.limit.Rate > 0 {
+		b.tokens--
+	}
+	b.inFlight++
+}
+
+func (b *bucket) release() {
+	if b == nil {
+		return
+	}
+	b.inFlight--
+}
+
+func (b *bucket) idle(now time.Time) bool {
+	b.refill(now)
+	return b.inFlight == 0 && (b.limit.Rate <= 0 || b.tokens >= b.limit.burst())
+}
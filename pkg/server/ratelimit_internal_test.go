@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterSweep(t *testing.T) {
+	// given
+	now := time.Now()
+	l := newLimiter(RateLimits{
+		PerSession: RateLimit{Rate: 1, Burst: 1, MaxInFlight: 1},
+	}, []ToolHandler{{Tool: api.NewTool("my-tool")}})
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	release1, err := l.acquire("session-1", "my-tool")
+	require.NoError(t, err)
+	release1()
+	_, err = l.acquire("session-2", "my-tool") // still in flight
+	require.NoError(t, err)
+	require.Len(t, l.sessions, 2)
+
+	// when
+	now = now.Add(sweepInterval)
+	_, err = l.acquire("session-3", "my-tool")
+
+	// then
+	require.NoError(t, err)
+	assert.NotContains(t, l.sessions, "session-1")
+	assert.Contains(t, l.sessions, "session-2")
+	assert.Contains(t, l.sessions, "session-3")
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	// given
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	router := NewRouterBuilder("converse-mcp", "0.1", logger).Build()
+	h := NewHTTPHandler(router, logger, WithSessionIdleTimeout(time.Minute)).(*httpHandler)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	post := func(sessionID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set(SessionIDHeader, sessionID)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	sessionID := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`).Header().Get(SessionIDHeader)
+	require.NotEmpty(t, sessionID)
+	now = now.Add(30 * time.Second)
+	require.Equal(t, http.StatusOK, post(sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`).Code)
+
+	// when
+	now = now.Add(2 * time.Minute)
+	rec := post(sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+
+	// then
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, h.sessions)
+}
@@ -0,0 +1,287 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimits(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	t.Run("stdio", func(t *testing.T) {
+
+		// the server can handle 2 calls concurrently, regardless of the number of CPUs
+		start := func(t *testing.T, router server.Router) *mcptest.Client {
+			return mcptest.NewClient(t, router, mcptest.WithConcurrency(2))
+		}
+		callTool := func(cl *mcptest.Client, name string) error {
+			_, err := cl.CallTool(context.Background(), name, nil)
+			return err
+		}
+		requireRateLimited := func(t *testing.T, err error, expectedData string) {
+			var rpcErr *jrpc2.Error
+			require.ErrorAs(t, err, &rpcErr)
+			assert.Equal(t, server.RateLimitedCode, rpcErr.Code)
+			assert.JSONEq(t, expectedData, string(rpcErr.Data))
+		}
+
+		t.Run("tool rate limit", func(t *testing.T) {
+			// given
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(api.NewTool("limited-tool"), EmptyToolHandle, server.WithToolRateLimit(server.RateLimit{Rate: 0.001, Burst: 2})).
+				WithTool(api.NewTool("unlimited-tool"), EmptyToolHandle).
+				Build()
+			cl := start(t, router)
+
+			// when
+			err1 := callTool(cl, "limited-tool")
+			err2 := callTool(cl, "limited-tool")
+			err3 := callTool(cl, "limited-tool")
+			err4 := callTool(cl, "unlimited-tool")
+
+			// then
+			require.NoError(t, err1)
+			require.NoError(t, err2)
+			requireRateLimited(t, err3, `{"scope":"tool","limit":"rate","retryAfter":1000}`)
+			require.NoError(t, err4)
+		})
+
+		t.Run("tool rate limit overrides default per-tool limit", func(t *testing.T) {
+			// given
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(api.NewTool("overridden-tool"), EmptyToolHandle, server.WithToolRateLimit(server.RateLimit{Rate: 0.001, Burst: 3})).
+				WithTool(api.NewTool("default-tool"), EmptyToolHandle).
+				WithRateLimits(server.RateLimits{
+					PerTool: server.RateLimit{Rate: 0.001, Burst: 1},
+				}).
+				Build()
+			cl := start(t, router)
+
+			// when/then
+			for range 3 {
+				require.NoError(t, callTool(cl, "overridden-tool"))
+			}
+			requireRateLimited(t, callTool(cl, "overridden-tool"), `{"scope":"tool","limit":"rate","retryAfter":1000}`)
+			require.NoError(t, callTool(cl, "default-tool"))
+			requireRateLimited(t, callTool(cl, "default-tool"), `{"scope":"tool","limit":"rate","retryAfter":1000}`)
+		})
+
+		t.Run("global max in flight", func(t *testing.T) {
+			// given
+			started := make(chan struct{})
+			done := make(chan struct{})
+			blockingHandle := func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				close(started)
+				select {
+				case <-done:
+				case <-ctx.Done():
+				}
+				return api.CallToolResult{}, nil
+			}
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(api.NewTool("blocking-tool"), blockingHandle).
+				WithTool(api.NewTool("other-tool"), EmptyToolHandle).
+				WithRateLimits(server.RateLimits{
+					Global: server.RateLimit{MaxInFlight: 1},
+				}).
+				Build()
+			cl := start(t, router)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- callTool(cl, "blocking-tool")
+			}()
+			<-started
+
+			// when
+			err := callTool(cl, "other-tool")
+
+			// then
+			requireRateLimited(t, err, `{"scope":"global","limit":"inFlight"}`)
+
+			// when the blocking call completes
+			close(done)
+			require.NoError(t, <-errs)
+
+			// then
+			require.NoError(t, callTool(cl, "other-tool"))
+		})
+
+		t.Run("max in flight released on handler error", func(t *testing.T) {
+			// given
+			failingHandle := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				return api.CallToolResult{}, errors.New("mock error")
+			}
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(api.NewTool("failing-tool"), failingHandle, server.WithToolRateLimit(server.RateLimit{MaxInFlight: 1})).
+				Build()
+			cl := start(t, router)
+
+			// when/then
+			for range 3 {
+				result, err := cl.CallTool(context.Background(), "failing-tool", nil)
+				require.NoError(t, err)
+				mcptest.AssertToolError(t, result)
+				mcptest.AssertTextContent(t, result, "mock error")
+			}
+		})
+
+		t.Run("per-session limit not applied without session", func(t *testing.T) {
+			// given
+			router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+				WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+				WithRateLimits(server.RateLimits{
+					PerSession: server.RateLimit{Rate: 0.001, Burst: 1},
+				}).
+				Build()
+			cl := start(t, router)
+
+			// when/then
+			require.NoError(t, callTool(cl, "my-tool"))
+			require.NoError(t, callTool(cl, "my-tool"))
+		})
+	})
+
+	t.Run("http", func(t *testing.T) {
+
+		post := func(t *testing.T, url, sessionID, body string) *http.Response {
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBufferString(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if sessionID != "" {
+				req.Header.Set(server.SessionIDHeader, sessionID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			return resp
+		}
+		initialize := func(t *testing.T, url string) string {
+			resp := post(t, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			sessionID := resp.Header.Get(server.SessionIDHeader)
+			require.NotEmpty(t, sessionID)
+			return sessionID
+		}
+		const toolCall = `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"my-tool"}}`
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+			WithRateLimits(server.RateLimits{
+				PerSession: server.RateLimit{Rate: 0.5, Burst: 1},
+			}).
+			Build()
+
+		t.Run("per-session limit", func(t *testing.T) {
+			// given
+			httpSrv := httptest.NewServer(server.NewHTTPHandler(router, logger))
+			defer httpSrv.Close()
+			session1 := initialize(t, httpSrv.URL)
+			session2 := initialize(t, httpSrv.URL)
+			require.NotEqual(t, session1, session2)
+
+			// when
+			resp1 := post(t, httpSrv.URL, session1, toolCall)
+			resp2 := post(t, httpSrv.URL, session1, toolCall)
+			resp3 := post(t, httpSrv.URL, session2, toolCall)
+
+			// then
+			assert.Equal(t, http.StatusOK, resp1.StatusCode)
+			assert.Equal(t, http.StatusTooManyRequests, resp2.StatusCode)
+			assert.Equal(t, "2", resp2.Header.Get("Retry-After"))
+			assert.Equal(t, http.StatusOK, resp3.StatusCode)
+		})
+
+		t.Run("per-session limit in batch", func(t *testing.T) {
+			const batch = `[
+				{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"my-tool"}},
+				{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"my-tool"}}
+			]`
+
+			t.Run("partially limited", func(t *testing.T) {
+				// given
+				httpSrv := httptest.NewServer(server.NewHTTPHandler(router, logger))
+				defer httpSrv.Close()
+				sessionID := initialize(t, httpSrv.URL)
+				req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, httpSrv.URL, bytes.NewBufferString(batch))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(server.SessionIDHeader, sessionID)
+
+				// when
+				resp, err := http.DefaultClient.Do(req)
+
+				// then
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Empty(t, resp.Header.Get("Retry-After"))
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				var resps []struct {
+					ID     int             `json:"id"`
+					Result json.RawMessage `json:"result"`
+					Error  *struct {
+						Code int `json:"code"`
+					} `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(body, &resps))
+				require.Len(t, resps, 2)
+				limited := 0
+				for _, r := range resps {
+					if r.Error != nil {
+						assert.Equal(t, int(server.RateLimitedCode), r.Error.Code)
+						limited++
+					} else {
+						assert.NotEmpty(t, r.Result)
+					}
+				}
+				assert.Equal(t, 1, limited)
+			})
+
+			t.Run("fully limited", func(t *testing.T) {
+				// given
+				httpSrv := httptest.NewServer(server.NewHTTPHandler(router, logger))
+				defer httpSrv.Close()
+				sessionID := initialize(t, httpSrv.URL)
+				require.Equal(t, http.StatusOK, post(t, httpSrv.URL, sessionID, toolCall).StatusCode)
+
+				// when
+				resp := post(t, httpSrv.URL, sessionID, batch)
+
+				// then
+				assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+				assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+			})
+		})
+
+		t.Run("max sessions", func(t *testing.T) {
+			// given
+			httpSrv := httptest.NewServer(server.NewHTTPHandler(router, logger, server.WithMaxSessions(1)))
+			defer httpSrv.Close()
+			initialize(t, httpSrv.URL)
+
+			// when
+			resp := post(t, httpSrv.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+
+			// then
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get(server.SessionIDHeader))
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// DefaultToolTimeout is the default maximum duration of a tool call
+const DefaultToolTimeout = time.Minute
+
+// WithToolTimeout sets the maximum duration of the calls of the tool, overriding the default timeout configured with
+// `RouterBuilder.WithDefaultToolTimeout`. A zero value disables the timeout.
+func WithToolTimeout(timeout time.Duration) ToolOption {
+	return func(h *ToolHandler) {
+		h.Timeout = &timeout
+	}
+}
+
+// WithDefaultToolTimeout sets the maximum duration of the calls of the tools which do not declare their own timeout
+// (`DefaultToolTimeout` by default). A zero value disables the timeout.
+func (b *RouterBuilder) WithDefaultToolTimeout(timeout time.Duration) *RouterBuilder {
+	b.toolTimeout = timeout
+	return b
+}
+
+// withRecovery converts the panics of the given handler into internal errors, so that they do not stop the server
+func withRecovery(h jrpc2.Handler, logger *slog.Logger) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (result any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("handler panicked", "method", req.Method(), "panic", r, "stack", string(debug.Stack()))
+				result, err = nil, jrpc2.Errorf(jrpc2.InternalError, "internal error while handling '%s'", req.Method())
+			}
+		}()
+		return h(ctx, req)
+	}
+}
+
+// runTool runs the handler of the tool in a separate goroutine, so that the call returns when the timeout expires
+// even if the handler ignores the cancellation of its context (in which case it keeps running until it returns).
+// The given release func is called when the handler returns, so that a handler which keeps running after the call
+// returned still counts against the limits of calls in flight.
+// Timeouts and panics are reported in results with the `isError` flag set.
+func runTool(ctx context.Context, h ToolHandler, params api.CallToolRequestParams, timeout time.Duration, release func(), logger *slog.Logger) (api.CallToolResult, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	type outcome struct {
+		result api.CallToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("tool panicked", "name", params.Name, "panic", r, "stack", string(debug.Stack()))
+				o = outcome{result: newToolErrorResult(fmt.Sprintf("tool '%s' failed with an internal error", params.Name))}
+			}
+			release() // before the outcome is sent, so that the slot is free when the response is received
+			done <- o
+		}()
+		o.result, o.err = h.Handle(ctx, params)
+	}()
+	select {
+	case o := <-done:
+		if o.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return toolTimeoutResult(params.Name, timeout, logger), nil
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return toolTimeoutResult(params.Name, timeout, logger), nil
+		}
+		return api.CallToolResult{}, ctx.Err() // cancelled by the client
+	}
+}
+
+func toolTimeoutResult(name string, timeout time.Duration, logger *slog.Logger) api.CallToolResult {
+	logger.Warn("tool timed out", "name", name, "timeout", timeout)
+	return newToolErrorResult(fmt.Sprintf("tool '%s' timed out after %s", name, timeout))
+}
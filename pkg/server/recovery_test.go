@@ -0,0 +1,151 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("panic"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+			panic("mock panic")
+		}).
+		WithPrompt(api.NewPrompt("panic"), func(_ context.Context, _ api.GetPromptRequestParams) (api.GetPromptResult, error) {
+			panic("mock panic")
+		}).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	t.Run("tool", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "panic", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolError(t, result)
+		mcptest.AssertTextContent(t, result, "tool 'panic' failed with an internal error")
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		// when
+		_, err := cl.GetPrompt(context.Background(), "panic", nil)
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, jrpc2.InternalError, rpcErr.Code)
+		assert.Equal(t, "internal error while handling 'prompts/get'", rpcErr.Message)
+	})
+
+	t.Run("server still running", func(t *testing.T) {
+		// when
+		_, err := cl.ListTools(context.Background())
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestToolTimeout(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	waitForCancellation := func(ctx context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		<-ctx.Done()
+		return api.CallToolResult{}, ctx.Err()
+	}
+	ignoreCancellation := func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+		time.Sleep(time.Second)
+		return api.CallToolResult{Content: []api.CallToolResultContentElem{}}, nil
+	}
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithDefaultToolTimeout(50*time.Millisecond).
+		WithTool(api.NewTool("slow"), waitForCancellation).
+		WithTool(api.NewTool("stuck"), ignoreCancellation).
+		WithTool(api.NewTool("slower"), waitForCancellation, server.WithToolTimeout(100*time.Millisecond)).
+		Build()
+	cl := mcptest.NewClient(t, router, mcptest.WithConcurrency(2))
+
+	t.Run("default timeout", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "slow", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolError(t, result)
+		mcptest.AssertTextContent(t, result, "tool 'slow' timed out after 50ms")
+	})
+
+	t.Run("handler ignoring the cancellation", func(t *testing.T) {
+		// when
+		start := time.Now()
+		result, err := cl.CallTool(context.Background(), "stuck", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		mcptest.AssertTextContent(t, result, "tool 'stuck' timed out after 50ms")
+	})
+
+	t.Run("tool timeout", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "slower", nil)
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "tool 'slower' timed out after 100ms")
+	})
+
+	t.Run("handler ignoring the cancellation counts against the calls in flight", func(t *testing.T) {
+		// given
+		unblock := make(chan struct{})
+		returned := make(chan struct{})
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithDefaultToolTimeout(50*time.Millisecond).
+			WithTool(api.NewTool("stuck"), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				defer close(returned)
+				<-unblock
+				return api.CallToolResult{Content: []api.CallToolResultContentElem{}}, nil
+			}).
+			WithTool(api.NewTool("other"), EmptyToolHandle).
+			WithRateLimits(server.RateLimits{
+				Global: server.RateLimit{MaxInFlight: 1},
+			}).
+			Build()
+		cl := mcptest.NewClient(t, router, mcptest.WithConcurrency(2))
+		result, err := cl.CallTool(context.Background(), "stuck", nil)
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "tool 'stuck' timed out after 50ms")
+
+		// when
+		_, err = cl.CallTool(context.Background(), "other", nil)
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, server.RateLimitedCode, rpcErr.Code)
+
+		// when the handler eventually returns
+		close(unblock)
+		<-returned
+
+		// then
+		require.Eventually(t, func() bool {
+			_, err := cl.CallTool(context.Background(), "other", nil)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	})
+}
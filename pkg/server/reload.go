@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync/atomic"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ReloadableRouter serves the registrations of the builder which was last passed to `Reload`, so that prompts,
+// resources and tools can be changed while clients are connected. The clients are notified of the changes with the
+// `notifications/*/list_changed` notifications, and of the updates of the resources they subscribed to with the
+// `notifications/resources/updated` notifications.
+type ReloadableRouter struct {
+	current       atomic.Pointer[registry]
+	subscriptions *Subscriptions
+}
+
+// registry is a snapshot of the registrations of a builder
+type registry struct {
+	router  Router
+	prompts []api.Prompt
+	listed  []api.Resource
+	tools   []api.Tool
+}
+
+// NewReloadableRouter returns a router which serves the registrations of the given builder.
+// It returns an error if the registrations are invalid (see `RouterBuilder.Validate`).
+func NewReloadableRouter(b *RouterBuilder, logger *slog.Logger) (*ReloadableRouter, error) {
+	r := &ReloadableRouter{
+		subscriptions: NewSubscriptions(logger),
+	}
+	reg, err := r.newRegistry(b)
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(reg)
+	return r, nil
+}
+
+func (r *ReloadableRouter) newRegistry(b *RouterBuilder) (*registry, error) {
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid router:\n%w", err)
+	}
+	// the lists may change after the clients were initialized, and the subscriptions are kept across the reloads
+	b.capabilities.Prompts.ListChanged = api.BoolPtr(true)
+	b.capabilities.Resources.ListChanged = api.BoolPtr(true)
+	b.capabilities.Tools.ListChanged = api.BoolPtr(true)
+	b.WithSubscriptions(r.subscriptions)
+	reg := &registry{
+		router:  b.Build(),
+		prompts: make([]api.Prompt, 0, len(b.prompts)),
+		listed:  make([]api.Resource, 0, len(b.resources)),
+		tools:   make([]api.Tool, 0, len(b.tools)),
+	}
+	for _, h := range b.prompts {
+		reg.prompts = append(reg.prompts, h.Prompt)
+	}
+	for _, h := range b.resources {
+		reg.listed = append(reg.listed, h.Resource)
+	}
+	for _, h := range b.tools {
+		reg.tools = append(reg.tools, h.Tool)
+	}
+	return reg, nil
+}
+
+// Router returns the router to serve, whose handlers delegate to the registrations which are currently loaded
+func (r *ReloadableRouter) Router() Router {
+	router := Router{}
+	for method := range r.current.Load().router {
+		router[method] = func(ctx context.Context, req *jrpc2.Request) (any, error) {
+			return r.current.Load().router[method](ctx, req)
+		}
+	}
+	return router
+}
+
+// Reload replaces the registrations with the ones of the given builder, and notifies the clients of the changes.
+// The given URIs are the ones of the resources whose contents changed, whose subscribers are notified.
+// If the registrations are invalid, the current ones are kept and an error is returned.
+func (r *ReloadableRouter) Reload(b *RouterBuilder, updated ...string) error {
+	reg, err := r.newRegistry(b)
+	if err != nil {
+		return err
+	}
+	previous := r.current.Swap(reg)
+	if !reflect.DeepEqual(previous.prompts, reg.prompts) {
+		r.subscriptions.Notify("notifications/prompts/list_changed", nil)
+	}
+	if !reflect.DeepEqual(previous.listed, reg.listed) {
+		r.subscriptions.Notify("notifications/resources/list_changed", nil)
+	}
+	if !reflect.DeepEqual(previous.tools, reg.tools) {
+		r.subscriptions.Notify("notifications/tools/list_changed", nil)
+	}
+	// the resources whose definition changed are also considered as updated
+	resources := make(map[string]api.Resource, len(previous.listed))
+	for _, res := range previous.listed {
+		resources[res.Uri] = res
+	}
+	for _, res := range reg.listed {
+		if p, found := resources[res.Uri]; found && !reflect.DeepEqual(p, res) && !slices.Contains(updated, res.Uri) {
+			updated = append(updated, res.Uri)
+		}
+	}
+	for _, uri := range updated {
+		r.subscriptions.NotifyResourceUpdated(uri)
+	}
+	return nil
+}
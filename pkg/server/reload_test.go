@@ -0,0 +1,151 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadableRouter(t *testing.T) {
+
+	logger := slog.New(slog.DiscardHandler)
+	newBuilder := func(motd string, tools ...string) *server.RouterBuilder {
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithResource(api.NewResource("motd", "text:///motd"), TextResourceHandle(motd))
+		for _, name := range tools {
+			b.WithTool(api.NewTool(name), func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				return api.CallToolResult{
+					Content: []api.CallToolResultContentElem{api.Text(name)},
+				}, nil
+			})
+		}
+		return b
+	}
+
+	t.Run("declare the capabilities", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(server.NewRouterBuilder("converse-mcp", "0.1", logger), logger)
+		require.NoError(t, err)
+
+		// when
+		cl := mcptest.NewClient(t, r.Router())
+
+		// then
+		capabilities := cl.InitializeResult.Capabilities
+		assert.True(t, *capabilities.Prompts.ListChanged)
+		assert.True(t, *capabilities.Resources.ListChanged)
+		assert.True(t, *capabilities.Resources.Subscribe)
+		assert.True(t, *capabilities.Tools.ListChanged)
+	})
+
+	t.Run("reload the tools", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(newBuilder("hello", "first"), logger)
+		require.NoError(t, err)
+		cl := mcptest.NewClient(t, r.Router())
+
+		// when
+		err = r.Reload(newBuilder("hello", "first", "second"))
+
+		// then
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = cl.WaitForNotification(ctx, "notifications/tools/list_changed")
+		require.NoError(t, err)
+		tools, err := cl.ListTools(context.Background())
+		require.NoError(t, err)
+		require.Len(t, tools.Tools, 2)
+		assert.Equal(t, "second", tools.Tools[1].Name)
+		result, err := cl.CallTool(context.Background(), "second", nil)
+		require.NoError(t, err)
+		assert.Equal(t, api.Text("second"), result.Content[0])
+		// other lists did not change
+		for _, n := range cl.Notifications() {
+			assert.Equal(t, "notifications/tools/list_changed", n.Method)
+		}
+	})
+
+	t.Run("notify the subscribers", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(newBuilder("hello"), logger)
+		require.NoError(t, err)
+		subscriber := mcptest.NewClient(t, r.Router())
+		require.NoError(t, subscriber.Subscribe(context.Background(), "text:///motd"))
+		other := mcptest.NewClient(t, r.Router())
+
+		// when
+		err = r.Reload(newBuilder("bonjour"), "text:///motd")
+
+		// then
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		n, err := subscriber.WaitForNotification(ctx, "notifications/resources/updated")
+		require.NoError(t, err)
+		params := api.ResourceUpdatedNotificationParams{}
+		require.NoError(t, n.UnmarshalParams(&params))
+		assert.Equal(t, "text:///motd", params.Uri)
+		result, err := subscriber.ReadResource(context.Background(), "text:///motd")
+		require.NoError(t, err)
+		assert.Equal(t, "bonjour", result.Contents[0].Text)
+		assert.Empty(t, other.Notifications())
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(newBuilder("hello"), logger)
+		require.NoError(t, err)
+		cl := mcptest.NewClient(t, r.Router())
+		require.NoError(t, cl.Subscribe(context.Background(), "text:///motd"))
+
+		// when
+		err = cl.Unsubscribe(context.Background(), "text:///motd")
+
+		// then
+		require.NoError(t, err)
+		require.NoError(t, r.Reload(newBuilder("bonjour"), "text:///motd"))
+		assert.Empty(t, cl.Notifications())
+	})
+
+	t.Run("subscribe to unknown resource", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(newBuilder("hello"), logger)
+		require.NoError(t, err)
+		cl := mcptest.NewClient(t, r.Router())
+
+		// when
+		err = cl.Subscribe(context.Background(), "text:///unknown")
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, server.ResourceNotFoundCode, rpcErr.Code)
+	})
+
+	t.Run("keep the registrations when the new ones are invalid", func(t *testing.T) {
+		// given
+		r, err := server.NewReloadableRouter(newBuilder("hello", "first"), logger)
+		require.NoError(t, err)
+		cl := mcptest.NewClient(t, r.Router())
+
+		// when
+		err = r.Reload(newBuilder("hello", "first", "first"))
+
+		// then
+		require.ErrorContains(t, err, "tool 'first': duplicate name")
+		tools, err := cl.ListTools(context.Background())
+		require.NoError(t, err)
+		require.Len(t, tools.Tools, 1)
+		assert.Empty(t, cl.Notifications())
+	})
+}
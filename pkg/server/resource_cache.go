@@ -0,0 +1,245 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// CacheMetaKey is the key of the cache hints in the `_meta` of the results of the resource reads (see `WithCacheHints`)
+const CacheMetaKey = "converse-mcp/cache"
+
+// ErrNotModified is returned by the resource handlers when the version of the cached content (see
+// `CachedVersionFromContext`) is still current, so that the cached content is served again
+var ErrNotModified = errors.New("not modified")
+
+// CacheHints declares that the result of a resource read can be cached by a `ResourceCache`
+type CacheHints struct {
+	// MaxAge overrides the TTL of the cache when it is positive
+	MaxAge time.Duration
+	// Version identifies the content (eg: a hash or a revision), so that the handler can tell that the cached content
+	// is still current once it expired, by returning `ErrNotModified`
+	Version string
+	// LastModified is the time at which the content was last modified (optional)
+	LastModified time.Time
+}
+
+// WithCacheHints returns a copy of the given result whose `_meta` contains the given hints, which makes it cacheable.
+// The hints are also sent to the client: `maxAge` in seconds, `etag` and `lastModified` in the RFC 3339 format.
+func WithCacheHints(result api.ReadResourceResult, hints CacheHints) api.ReadResourceResult {
+	meta := make(map[string]any, len(result.Meta)+1)
+	for k, v := range result.Meta {
+		meta[k] = v
+	}
+	h := map[string]any{}
+	if hints.MaxAge > 0 {
+		h["maxAge"] = hints.MaxAge.Seconds()
+	}
+	if hints.Version != "" {
+		h["etag"] = hints.Version
+	}
+	if !hints.LastModified.IsZero() {
+		h["lastModified"] = hints.LastModified.UTC().Format(time.RFC3339)
+	}
+	meta[CacheMetaKey] = h
+	result.Meta = meta
+	return result
+}
+
+// cacheHints returns the hints in the `_meta` of the given result, and `false` if the result is not cacheable
+func cacheHints(result api.ReadResourceResult) (CacheHints, bool) {
+	h, ok := result.Meta[CacheMetaKey].(map[string]any)
+	if !ok {
+		return CacheHints{}, false
+	}
+	hints := CacheHints{}
+	if maxAge, ok := h["maxAge"].(float64); ok {
+		hints.MaxAge = time.Duration(maxAge * float64(time.Second))
+	}
+	hints.Version, _ = h["etag"].(string)
+	if lastModified, ok := h["lastModified"].(string); ok {
+		hints.LastModified, _ = time.Parse(time.RFC3339, lastModified)
+	}
+	return hints, true
+}
+
+type cachedVersionKey struct{}
+
+// CachedVersionFromContext returns the version of the cached content of the resource being read, when it expired.
+// The handler returns `ErrNotModified` if the version is still current.
+func CachedVersionFromContext(ctx context.Context) (string, bool) {
+	version, ok := ctx.Value(cachedVersionKey{}).(string)
+	return version, ok
+}
+
+// CacheOptions configures a `ResourceCache`. A zero value disables the corresponding bound.
+type CacheOptions struct {
+	// TTL is the duration during which the contents are served from the cache, unless the handler set a `MaxAge`
+	TTL time.Duration
+	// MaxEntries is the maximum number of cached resources. The least recently read ones are evicted first.
+	MaxEntries int
+	// MaxBytes is the maximum total size of the texts and blobs of the cached resources
+	MaxBytes int
+}
+
+// CacheStats are the counters of a `ResourceCache`
+type CacheStats struct {
+	Hits int
+	// Misses counts the reads which were handled by the handler, including the results which are not cacheable
+	Misses int
+	// Revalidations counts the expired entries which were served again because the handler returned `ErrNotModified`
+	Revalidations int
+	Evictions     int
+	Entries       int
+	Bytes         int
+}
+
+// ResourceCache caches the results of the resource reads by URI, for the handlers which declare them cacheable with
+// `WithCacheHints`. The cache is shared by all the sessions, so the contents must not depend on the session.
+// Use `Middleware` to add it to a builder, and `Invalidate` (eg: with `Subscriptions.OnResourceUpdated`) to discard
+// the contents which changed.
+type ResourceCache struct {
+	opts    CacheOptions
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// least recently read entries at the back
+	lru   *list.List
+	stats CacheStats
+}
+
+type cacheEntry struct {
+	uri     string
+	result  api.ReadResourceResult
+	hints   CacheHints
+	expires time.Time
+	size    int
+}
+
+// NewResourceCache returns an empty cache
+func NewResourceCache(opts CacheOptions) *ResourceCache {
+	return &ResourceCache{
+		opts:    opts,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Middleware returns the middleware which serves the cached contents (see `RouterBuilder.UseResource`)
+func (c *ResourceCache) Middleware() ResourceMiddleware {
+	return func(next ResourceHandleFunc) ResourceHandleFunc {
+		return func(ctx context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+			now := c.now()
+			c.mu.Lock()
+			var cached *cacheEntry
+			if elem, found := c.entries[params.Uri]; found {
+				cached = elem.Value.(*cacheEntry)
+				if now.Before(cached.expires) {
+					c.stats.Hits++
+					c.lru.MoveToFront(elem)
+					c.mu.Unlock()
+					return cached.copyResult(), nil
+				}
+			}
+			c.mu.Unlock()
+			if cached != nil && cached.hints.Version != "" {
+				ctx = context.WithValue(ctx, cachedVersionKey{}, cached.hints.Version)
+			}
+			result, err := next(ctx, params)
+			if cached != nil && errors.Is(err, ErrNotModified) {
+				c.mu.Lock()
+				c.stats.Revalidations++
+				c.mu.Unlock()
+				c.store(params.Uri, cached.result, cached.hints, now)
+				return cached.copyResult(), nil
+			}
+			c.mu.Lock()
+			c.stats.Misses++
+			c.mu.Unlock()
+			if err != nil {
+				return result, err
+			}
+			if hints, ok := cacheHints(result); ok {
+				c.store(params.Uri, result, hints, now)
+			}
+			return result, nil
+		}
+	}
+}
+
+func (e *cacheEntry) copyResult() api.ReadResourceResult {
+	result := e.result
+	result.Contents = slices.Clone(result.Contents)
+	return result
+}
+
+func (c *ResourceCache) store(uri string, result api.ReadResourceResult, hints CacheHints, now time.Time) {
+	ttl := c.opts.TTL
+	if hints.MaxAge > 0 {
+		ttl = hints.MaxAge
+	}
+	size := 0
+	for _, contents := range result.Contents {
+		size += len(contents.Text) + len(contents.Blob)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(uri)
+	if ttl <= 0 || (c.opts.MaxBytes > 0 && size > c.opts.MaxBytes) {
+		return
+	}
+	c.entries[uri] = c.lru.PushFront(&cacheEntry{
+		uri:     uri,
+		result:  result,
+		hints:   hints,
+		expires: now.Add(ttl),
+		size:    size,
+	})
+	c.stats.Entries++
+	c.stats.Bytes += size
+	for (c.opts.MaxEntries > 0 && c.stats.Entries > c.opts.MaxEntries) || (c.opts.MaxBytes > 0 && c.stats.Bytes > c.opts.MaxBytes) {
+		c.remove(c.lru.Back().Value.(*cacheEntry).uri)
+		c.stats.Evictions++
+	}
+}
+
+// remove discards the entry of the given URI, if any. It must be called with the lock held.
+func (c *ResourceCache) remove(uri string) {
+	elem, found := c.entries[uri]
+	if !found {
+		return
+	}
+	c.lru.Remove(elem)
+	delete(c.entries, uri)
+	c.stats.Entries--
+	c.stats.Bytes -= elem.Value.(*cacheEntry).size
+}
+
+// Invalidate discards the cached content of the resource with the given URI
+func (c *ResourceCache) Invalidate(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(uri)
+}
+
+// InvalidateAll discards all the cached contents
+func (c *ResourceCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uri := range c.entries {
+		c.remove(uri)
+	}
+}
+
+// Stats returns the current counters of the cache
+func (c *ResourceCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceCacheExpiration(t *testing.T) {
+	// given
+	now := time.Now()
+	cache := NewResourceCache(CacheOptions{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+	version := "v1"
+	calls := 0
+	versions := []string{}
+	read := cache.Middleware()(func(ctx context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+		calls++
+		if v, ok := CachedVersionFromContext(ctx); ok {
+			versions = append(versions, v)
+			if v == version {
+				return api.ReadResourceResult{}, ErrNotModified
+			}
+		}
+		return WithCacheHints(api.ReadResourceResult{
+			Contents: []api.ReadResourceResultContentsElem{{Uri: params.Uri, Text: version}},
+		}, CacheHints{Version: version}), nil
+	})
+	readText := func(t *testing.T) string {
+		result, err := read(context.Background(), api.ReadResourceRequestParams{Uri: "text:///motd"})
+		require.NoError(t, err)
+		return result.Contents[0].Text
+	}
+	require.Equal(t, "v1", readText(t))
+
+	t.Run("revalidate", func(t *testing.T) {
+		// given
+		now = now.Add(time.Minute)
+
+		// when
+		text := readText(t)
+
+		// then
+		assert.Equal(t, "v1", text)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, []string{"v1"}, versions)
+		assert.Equal(t, 1, cache.Stats().Revalidations)
+		// the entry is fresh again
+		assert.Equal(t, "v1", readText(t))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		// given
+		now = now.Add(time.Minute)
+		version = "v2"
+
+		// when
+		text := readText(t)
+
+		// then
+		assert.Equal(t, "v2", text)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []string{"v1", "v1"}, versions)
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		// given
+		cache := NewResourceCache(CacheOptions{TTL: time.Minute})
+		cache.now = func() time.Time { return now }
+		calls := 0
+		read := cache.Middleware()(func(_ context.Context, _ api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+			calls++
+			return WithCacheHints(api.ReadResourceResult{}, CacheHints{MaxAge: time.Hour}), nil
+		})
+		_, err := read(context.Background(), api.ReadResourceRequestParams{Uri: "text:///motd"})
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(30 * time.Minute)
+		_, err = read(context.Background(), api.ReadResourceRequestParams{Uri: "text:///motd"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
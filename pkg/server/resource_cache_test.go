@@ -0,0 +1,145 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceCache(t *testing.T) {
+
+	logger := slog.New(slog.DiscardHandler)
+	// countingHandle returns the number of calls as the text of the resource
+	countingHandle := func(calls *atomic.Int32, hints *server.CacheHints) server.ResourceHandleFunc {
+		return func(_ context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+			result := api.ReadResourceResult{
+				Contents: []api.ReadResourceResultContentsElem{
+					{
+						Uri:  params.Uri,
+						Text: strings.Repeat("x", int(calls.Add(1))),
+					},
+				},
+			}
+			if hints != nil {
+				result = server.WithCacheHints(result, *hints)
+			}
+			return result, nil
+		}
+	}
+
+	t.Run("cache the cacheable results", func(t *testing.T) {
+		// given
+		cache := server.NewResourceCache(server.CacheOptions{TTL: time.Minute})
+		cacheable, uncacheable := &atomic.Int32{}, &atomic.Int32{}
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			UseResource(cache.Middleware()).
+			WithResource(api.NewResource("cacheable", "text:///cacheable"), countingHandle(cacheable, &server.CacheHints{
+				Version:      "v1",
+				LastModified: time.Date(2025, 6, 18, 10, 0, 0, 0, time.UTC),
+			})).
+			WithResource(api.NewResource("uncacheable", "text:///uncacheable"), countingHandle(uncacheable, nil)).
+			Build()
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		for range 3 {
+			_, err := cl.ReadResource(context.Background(), "text:///cacheable")
+			require.NoError(t, err)
+			_, err = cl.ReadResource(context.Background(), "text:///uncacheable")
+			require.NoError(t, err)
+		}
+
+		// then
+		assert.Equal(t, int32(1), cacheable.Load())
+		assert.Equal(t, int32(3), uncacheable.Load())
+		assert.Equal(t, server.CacheStats{Hits: 2, Misses: 4, Entries: 1, Bytes: 1}, cache.Stats())
+		// the hints are sent to the client
+		result, err := cl.ReadResource(context.Background(), "text:///cacheable")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"etag":         "v1",
+			"lastModified": "2025-06-18T10:00:00Z",
+		}, result.Meta[server.CacheMetaKey])
+	})
+
+	t.Run("invalidate on update", func(t *testing.T) {
+		// given
+		cache := server.NewResourceCache(server.CacheOptions{TTL: time.Minute})
+		subscriptions := server.NewSubscriptions(logger)
+		subscriptions.OnResourceUpdated(cache.Invalidate)
+		calls := &atomic.Int32{}
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			UseResource(cache.Middleware()).
+			WithSubscriptions(subscriptions).
+			WithResource(api.NewResource("motd", "text:///motd"), countingHandle(calls, &server.CacheHints{})).
+			Build()
+		cl := mcptest.NewClient(t, router)
+		_, err := cl.ReadResource(context.Background(), "text:///motd")
+		require.NoError(t, err)
+
+		// when
+		subscriptions.NotifyResourceUpdated("text:///motd")
+
+		// then
+		result, err := cl.ReadResource(context.Background(), "text:///motd")
+		require.NoError(t, err)
+		assert.Equal(t, "xx", result.Contents[0].Text)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("evict the least recently read entries", func(t *testing.T) {
+		// given
+		cache := server.NewResourceCache(server.CacheOptions{TTL: time.Minute, MaxEntries: 2, MaxBytes: 4})
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).UseResource(cache.Middleware())
+		calls := map[string]*atomic.Int32{}
+		for _, name := range []string{"a", "b", "c"} {
+			calls[name] = &atomic.Int32{}
+			b.WithResource(api.NewResource(name, "text:///"+name), countingHandle(calls[name], &server.CacheHints{}))
+		}
+		cl := mcptest.NewClient(t, b.Build())
+
+		// when
+		for _, name := range []string{"a", "b", "a", "c", "a", "b"} {
+			_, err := cl.ReadResource(context.Background(), "text:///"+name)
+			require.NoError(t, err)
+		}
+
+		// then
+		assert.Equal(t, int32(1), calls["a"].Load())
+		assert.Equal(t, int32(2), calls["b"].Load()) // evicted when `c` was cached
+		assert.Equal(t, int32(1), calls["c"].Load())
+		stats := cache.Stats()
+		assert.Equal(t, 2, stats.Hits)
+		assert.Equal(t, 2, stats.Evictions)
+		assert.Equal(t, 2, stats.Entries)
+	})
+
+	t.Run("do not cache the contents larger than the cache", func(t *testing.T) {
+		// given
+		cache := server.NewResourceCache(server.CacheOptions{TTL: time.Minute, MaxBytes: 1})
+		calls := &atomic.Int32{}
+		calls.Store(1) // the contents have 2 bytes
+		router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			UseResource(cache.Middleware()).
+			WithResource(api.NewResource("motd", "text:///motd"), countingHandle(calls, &server.CacheHints{})).
+			Build()
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		_, err := cl.ReadResource(context.Background(), "text:///motd")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 0, cache.Stats().Entries)
+	})
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"context"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ResourceProvider provides a dynamic set of resources, whose URIs start with the same prefix
+// (eg: the files of a directory, see `FSProvider`)
+type ResourceProvider interface {
+	// Prefix returns the prefix of the URIs of the resources
+	Prefix() string
+	// ListResources returns the resources to list in the responses to the `resources/list` requests
+	ListResources(ctx context.Context) ([]api.Resource, error)
+	// ReadResource reads the resource with the given URI
+	ReadResource(ctx context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error)
+}
+
+// WithResourceProvider lists the resources of the given provider after the registered ones, and handles the reads
+// of the resources whose URI starts with its prefix (see `WithResourcePrefix`)
+func (b *RouterBuilder) WithResourceProvider(p ResourceProvider, opts ...ResourceOption) *RouterBuilder {
+	b.logger.Debug("with resource provider", "prefix", p.Prefix())
+	b.resourceProviders = append(b.resourceProviders, p)
+	return b.WithResourcePrefix(p.Prefix(), p.ReadResource, opts...)
+}
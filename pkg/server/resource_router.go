@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ResourceMatcher returns true if the resource with the given URI can be read by the associated handler
+type ResourceMatcher func(uri string) bool
+
+// ResourceRoute handles the reads of the resources which are not listed, and whose URI starts with the `Prefix`
+// or is accepted by the `Match` func
+type ResourceRoute struct {
+	Prefix string
+	Match  ResourceMatcher
+	Handle ResourceHandleFunc
+}
+
+// WithResourcePrefix handles the reads of the resources whose URI starts with the given prefix (eg: `file:///docs/`).
+// These resources are not listed in the response to the `resources/list` requests.
+// When several prefixes match a URI, the longest one is used.
+func (b *RouterBuilder) WithResourcePrefix(prefix string, handle ResourceHandleFunc, opts ...ResourceOption) *RouterBuilder {
+	b.logger.Debug("with resource prefix", "prefix", prefix)
+	return b.withResourceRoute(ResourceRoute{
+		Prefix: prefix,
+		Match: func(uri string) bool {
+			return strings.HasPrefix(uri, prefix)
+		},
+		Handle: handle,
+	}, opts)
+}
+
+// WithResourceMatcher handles the reads of the resources whose URI is accepted by the given matcher.
+// These resources are not listed in the response to the `resources/list` requests.
+// Matchers are only used for the URIs which match neither a resource nor a prefix, in the order in which they were added.
+func (b *RouterBuilder) WithResourceMatcher(match ResourceMatcher, handle ResourceHandleFunc, opts ...ResourceOption) *RouterBuilder {
+	b.logger.Debug("with resource matcher")
+	return b.withResourceRoute(ResourceRoute{
+		Match:  match,
+		Handle: handle,
+	}, opts)
+}
+
+func (b *RouterBuilder) withResourceRoute(route ResourceRoute, opts []ResourceOption) *RouterBuilder {
+	h := ResourceHandler{
+		Handle: route.Handle,
+	}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	route.Handle = h.Handle
+	b.resourceRoutes = append(b.resourceRoutes, route)
+	// Servers that support resources MUST declare the resources capability
+	b.capabilities.Resources.ListChanged = api.BoolPtr(true)
+	return b
+}
+
+// resourceRouter finds the handler of a resource by its URI: resources are matched on their exact URI first,
+// then on the longest prefix, then on the custom matchers
+type resourceRouter struct {
+	resources map[string]ResourceHandleFunc
+	prefixes  []ResourceRoute // sorted by decreasing length
+	matchers  []ResourceRoute
+}
+
+// newResourceRouter returns a router for the given resources and routes, or an error if several handlers
+// are registered for the same URI or the same prefix
+func newResourceRouter(handlers []ResourceHandler, routes []ResourceRoute) (*resourceRouter, error) {
+	r := &resourceRouter{
+		resources: make(map[string]ResourceHandleFunc, len(handlers)),
+	}
+	for _, h := range handlers {
+		if _, exists := r.resources[h.Resource.Uri]; exists {
+			return nil, fmt.Errorf("duplicate resource URI: '%s'", h.Resource.Uri)
+		}
+		r.resources[h.Resource.Uri] = h.Handle
+	}
+	for _, route := range routes {
+		if route.Prefix == "" {
+			r.matchers = append(r.matchers, route)
+			continue
+		}
+		if slices.ContainsFunc(r.prefixes, func(p ResourceRoute) bool { return p.Prefix == route.Prefix }) {
+			return nil, fmt.Errorf("duplicate resource prefix: '%s'", route.Prefix)
+		}
+		r.prefixes = append(r.prefixes, route)
+	}
+	slices.SortStableFunc(r.prefixes, func(a, b ResourceRoute) int {
+		return len(b.Prefix) - len(a.Prefix)
+	})
+	return r, nil
+}
+
+func (r *resourceRouter) lookup(uri string) (ResourceHandleFunc, bool) {
+	if h, ok := r.resources[uri]; ok {
+		return h, true
+	}
+	for _, route := range r.prefixes {
+		if route.Match(uri) {
+			return route.Handle, true
+		}
+	}
+	for _, route := range r.matchers {
+		if route.Match(uri) {
+			return route.Handle, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceRouter(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithResource(api.NewResource("readme", "file:///docs/README.md"), TextResourceHandle("exact")).
+		WithResourcePrefix("file:///docs/", TextResourceHandle("docs")).
+		WithResourcePrefix("file:///docs/api/", TextResourceHandle("api docs")).
+		WithResourceMatcher(func(uri string) bool {
+			return strings.HasSuffix(uri, ".md")
+		}, TextResourceHandle("markdown")).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	for uri, expected := range map[string]string{
+		"file:///docs/README.md":       "exact",
+		"file:///docs/index.html":      "docs",
+		"file:///docs/api/server.html": "api docs",
+		"file:///notes/todo.md":        "markdown",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			// when
+			result, err := cl.ReadResource(context.Background(), uri)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, result.Contents, 1)
+			assert.Equal(t, uri, result.Contents[0].Uri)
+			assert.Equal(t, expected, result.Contents[0].Text)
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		// when
+		_, err := cl.ReadResource(context.Background(), "file:///notes/todo.txt")
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, server.ResourceNotFoundCode, rpcErr.Code)
+	})
+
+	t.Run("only exact resources are listed", func(t *testing.T) {
+		// when
+		result, err := cl.ListResources(context.Background())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result.Resources, 1)
+		assert.Equal(t, "file:///docs/README.md", result.Resources[0].Uri)
+	})
+
+	t.Run("duplicate URI", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithResource(api.NewResource("first", "file:///README.md"), EmptyResourceHandle).
+			WithResource(api.NewResource("second", "file:///README.md"), EmptyResourceHandle)
+
+		// when
+		err := b.Validate()
+
+		// then
+		require.EqualError(t, err, "resource 'file:///README.md': duplicate URI")
+		assert.PanicsWithValue(t, "duplicate resource URI: 'file:///README.md'", func() { b.Build() })
+	})
+
+	t.Run("duplicate prefix", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+			WithResourcePrefix("file:///docs/", EmptyResourceHandle).
+			WithResourcePrefix("file:///docs/", EmptyResourceHandle)
+
+		// when
+		err := b.Validate()
+
+		// then
+		require.EqualError(t, err, "resource prefix 'file:///docs/': duplicate prefix")
+		assert.PanicsWithValue(t, "duplicate resource prefix: 'file:///docs/'", func() { b.Build() })
+	})
+}
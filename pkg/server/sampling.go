@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// ErrSamplingNotSupported is returned by `Sample` when the client did not declare the `sampling` capability
+var ErrSamplingNotSupported = errors.New("the client does not support sampling")
+
+// Sample asks the client to sample its LLM with a `sampling/createMessage` request, sent on the session of the request
+// being handled (eg: in a tool handler), and waits for the result.
+// It returns `ErrSamplingNotSupported` if the client did not declare the `sampling` capability, and an error wrapping
+// `ErrRequestsUnsupported` if the transport cannot send requests to the client (eg: on Streamable HTTP, when the
+// client did not open an event stream). When the context ends before the result is received, the request is
+// abandoned and the client is notified with a `notifications/cancelled` notification.
+func Sample(ctx context.Context, params api.CreateMessageRequestParams) (api.CreateMessageResult, error) {
+	capabilities, _ := ClientCapabilitiesFromContext(ctx)
+	if capabilities.Sampling == nil {
+		return api.CreateMessageResult{}, ErrSamplingNotSupported
+	}
+	result := api.CreateMessageResult{}
+	if err := callClient(ctx, "sampling/createMessage", params, &result); err != nil {
+		return api.CreateMessageResult{}, err
+	}
+	return result, nil
+}
+
+// callClient sends a request to the client on the session of the request being handled,
+// and decodes the result into the given value
+func callClient(ctx context.Context, method string, params, result any) error {
+	var data json.RawMessage
+	var err error
+	if s := sessionFromContext(ctx); s != nil && s.requests.attached() {
+		data, err = s.requests.call(ctx, method, params)
+	} else {
+		data, err = callback(ctx, method, params)
+	}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return fmt.Errorf("'%s' request abandoned: %w", method, ctx.Err())
+	case errors.Is(err, ErrRequestsUnsupported):
+		return fmt.Errorf("'%s' request not sent: %w", method, err)
+	case err != nil:
+		// not wrapped, so that the errors returned by the client are not returned as-is to the client of a tool
+		// (see `ToolResultFromError`)
+		return fmt.Errorf("'%s' request failed: %v", method, err) //nolint:errorlint // see above
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("invalid '%s' result: %w", method, err)
+	}
+	return nil
+}
+
+// callback sends the request with `jrpc2.Server.Callback`, when the server was not started on a channel returned by
+// `NewSessionChannel`
+func callback(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	srv := jrpc2.ServerFromContext(ctx)
+	if srv == nil {
+		return nil, errors.New("no server in context")
+	}
+	rsp, err := srv.Callback(ctx, method, params)
+	if errors.Is(err, jrpc2.ErrPushUnsupported) {
+		return nil, fmt.Errorf("%w: %w", ErrRequestsUnsupported, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(rsp.ResultString()), nil
+}
@@ -0,0 +1,354 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("summarize").WithInputProperty("text", api.TypeString, "the text to summarize", true),
+			func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+				if timeout, ok := params.Arguments["timeout"].(float64); ok {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+					defer cancel()
+				}
+				result, err := server.Sample(ctx, api.CreateMessageRequestParams{
+					MaxTokens:    100,
+					SystemPrompt: api.StringPtr("You summarize texts"),
+					Messages: []api.SamplingMessage{
+						{
+							Role:    api.RoleUser,
+							Content: api.Text(params.Arguments["text"].(string)).AsSamplingContent(),
+						},
+					},
+				})
+				if err != nil {
+					return api.CallToolResult{}, err
+				}
+				return api.CallToolResult{
+					Content: []api.CallToolResultContentElem{result.Content.ContentBlock()},
+				}, nil
+			}).
+		Build()
+
+	t.Run("sample", func(t *testing.T) {
+		// given
+		var received api.CreateMessageRequestParams
+		cl := mcptest.NewClient(t, router, mcptest.WithSampling(func(_ context.Context, params api.CreateMessageRequestParams) (api.CreateMessageResult, error) {
+			received = params
+			return api.CreateMessageResult{
+				Model:   "test-model",
+				Role:    api.RoleAssistant,
+				Content: api.CreateMessageResultContent{Type: api.ContentTypeText, Text: "a summary"},
+			}, nil
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "summarize", map[string]any{"text": "a long text"})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertTextContent(t, result, "a summary")
+		assert.Equal(t, 100, received.MaxTokens)
+		assert.Equal(t, "You summarize texts", *received.SystemPrompt)
+		require.Len(t, received.Messages, 1)
+		assert.Equal(t, api.Text("a long text"), received.Messages[0].Content.ContentBlock())
+	})
+
+	t.Run("sampling not supported", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		result, err := cl.CallTool(context.Background(), "summarize", map[string]any{"text": "a long text"})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		mcptest.AssertTextContent(t, result, server.ErrSamplingNotSupported.Error())
+	})
+
+	t.Run("sampling failed", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router, mcptest.WithSampling(func(_ context.Context, _ api.CreateMessageRequestParams) (api.CreateMessageResult, error) {
+			return api.CreateMessageResult{}, assert.AnError
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "summarize", map[string]any{"text": "a long text"})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		assert.Contains(t, result.Content[0].(api.TextContent).Text, "'sampling/createMessage' request failed")
+	})
+
+	t.Run("abandon on cancellation", func(t *testing.T) {
+		// given
+		cancelled := make(chan error, 1)
+		cl := mcptest.NewClient(t, router, mcptest.WithSampling(func(ctx context.Context, _ api.CreateMessageRequestParams) (api.CreateMessageResult, error) {
+			<-ctx.Done() // cancelled when the client is notified
+			cancelled <- ctx.Err()
+			return api.CreateMessageResult{}, ctx.Err()
+		}))
+
+		// when
+		result, err := cl.CallTool(context.Background(), "summarize", map[string]any{"text": "a long text", "timeout": 50})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, result.IsError)
+		assert.True(t, *result.IsError)
+		mcptest.AssertTextContent(t, result, "'sampling/createMessage' request abandoned: context deadline exceeded")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		n, err := cl.WaitForNotification(ctx, "notifications/cancelled")
+		require.NoError(t, err)
+		params := api.CancelledNotificationParams{}
+		require.NoError(t, n.UnmarshalParams(&params))
+		assert.True(t, params.RequestId.IsString())
+		require.NotNil(t, params.Reason)
+		assert.Equal(t, "context deadline exceeded", *params.Reason)
+		select {
+		case err := <-cancelled:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-ctx.Done():
+			assert.Fail(t, "the request was not cancelled on the client side")
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+
+		t.Run("sample", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"sampling":{}}`)
+			session.openEventStream()
+			results := session.callToolAsync("summarize", map[string]any{"text": "a long text"})
+
+			// when
+			req := session.receive()
+			status := session.respond(req.ID, api.CreateMessageResult{
+				Model:   "test-model",
+				Role:    api.RoleAssistant,
+				Content: api.CreateMessageResultContent{Type: api.ContentTypeText, Text: "a summary"},
+			})
+
+			// then
+			assert.Equal(t, "sampling/createMessage", req.Method)
+			received := api.CreateMessageRequestParams{}
+			require.NoError(t, json.Unmarshal(req.Params, &received))
+			assert.Equal(t, 100, received.MaxTokens)
+			assert.Equal(t, http.StatusAccepted, status)
+			result := <-results
+			require.NoError(t, result.err)
+			mcptest.AssertTextContent(t, result.CallToolResult, "a summary")
+		})
+
+		t.Run("no event stream", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"sampling":{}}`)
+
+			// when
+			result := <-session.callToolAsync("summarize", map[string]any{"text": "a long text"})
+
+			// then
+			require.NoError(t, result.err)
+			require.NotNil(t, result.IsError)
+			assert.True(t, *result.IsError)
+			mcptest.AssertTextContent(t, result.CallToolResult, "'sampling/createMessage' request not sent: "+
+				"the transport does not support requests to the client: no event stream opened by the client")
+		})
+
+		t.Run("abandon on cancellation", func(t *testing.T) {
+			// given
+			session := newHTTPTestSession(t, router, `{"sampling":{}}`)
+			session.openEventStream()
+
+			// when
+			results := session.callToolAsync("summarize", map[string]any{"text": "a long text", "timeout": 50})
+
+			// then
+			req := session.receive()
+			assert.Equal(t, "sampling/createMessage", req.Method)
+			n := session.receive()
+			assert.Equal(t, "notifications/cancelled", n.Method)
+			params := struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}{}
+			require.NoError(t, json.Unmarshal(n.Params, &params))
+			assert.JSONEq(t, string(req.ID), string(params.RequestID))
+			result := <-results
+			require.NoError(t, result.err)
+			mcptest.AssertTextContent(t, result.CallToolResult, "'sampling/createMessage' request abandoned: context deadline exceeded")
+			// the late response is accepted, but ignored
+			assert.Equal(t, http.StatusAccepted, session.respond(req.ID, api.CreateMessageResult{}))
+		})
+	})
+}
+
+// httpTestSession is a session of a client of the Streamable HTTP transport
+type httpTestSession struct {
+	t      *testing.T
+	url    string
+	id     string
+	events chan json.RawMessage
+}
+
+// serverMessage is a request or a notification received on the event stream of a session
+type serverMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// newHTTPTestSession starts an HTTP server for the given router, and initializes a session with the given
+// client capabilities
+func newHTTPTestSession(t *testing.T, router server.Router, capabilities string) *httpTestSession {
+	t.Helper()
+	httpSrv := httptest.NewServer(server.NewHTTPHandler(router, slog.New(slog.DiscardHandler)))
+	t.Cleanup(httpSrv.Close) // after the event stream is closed
+	s := &httpTestSession{
+		t:   t,
+		url: httpSrv.URL,
+	}
+	resp, data, err := s.post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18",` +
+		`"clientInfo":{"name":"test","version":"0.1"},"capabilities":` + capabilities + `}}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotContains(t, string(data), `"error"`)
+	s.id = resp.Header.Get(server.SessionIDHeader)
+	require.NotEmpty(t, s.id)
+	return s
+}
+
+func (s *httpTestSession) post(body string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.id != "" {
+		req.Header.Set(server.SessionIDHeader, s.id)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+type toolCallOutcome struct {
+	api.CallToolResult
+	err error
+}
+
+// callToolAsync calls the tool in a separate goroutine, so that the requests of the server can be answered
+func (s *httpTestSession) callToolAsync(name string, args map[string]any) <-chan toolCallOutcome {
+	outcome := make(chan toolCallOutcome, 1)
+	go func() {
+		body, err := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      2,
+			"method":  "tools/call",
+			"params":  api.CallToolRequestParams{Name: name, Arguments: args},
+		})
+		if err != nil {
+			outcome <- toolCallOutcome{err: err}
+			return
+		}
+		_, data, err := s.post(string(body))
+		if err != nil {
+			outcome <- toolCallOutcome{err: err}
+			return
+		}
+		resp := struct {
+			Result api.CallToolResult `json:"result"`
+		}{}
+		if err = json.Unmarshal(data, &resp); err != nil {
+			outcome <- toolCallOutcome{err: fmt.Errorf("invalid response '%s': %w", string(data), err)}
+			return
+		}
+		resp.Result.Content, err = api.DecodeContentBlocks(resp.Result.Content)
+		outcome <- toolCallOutcome{CallToolResult: resp.Result, err: err}
+	}()
+	return outcome
+}
+
+// respond sends the response to the request of the server with the given ID, and returns the HTTP status
+func (s *httpTestSession) respond(id json.RawMessage, result any) int {
+	s.t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	})
+	require.NoError(s.t, err)
+	resp, _, err := s.post(string(body))
+	require.NoError(s.t, err)
+	return resp.StatusCode
+}
+
+// openEventStream opens the event stream of the session, which is closed when the test completes
+func (s *httpTestSession) openEventStream() {
+	s.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	require.NoError(s.t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(server.SessionIDHeader, s.id)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(s.t, err)
+	require.Equal(s.t, http.StatusOK, resp.StatusCode)
+	require.Equal(s.t, "text/event-stream", resp.Header.Get("Content-Type"))
+	s.events = make(chan json.RawMessage, 16)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, found := strings.CutPrefix(scanner.Text(), "data: "); found {
+				s.events <- json.RawMessage(data)
+			}
+		}
+	}()
+}
+
+// receive returns the next message received on the event stream
+func (s *httpTestSession) receive() serverMessage {
+	s.t.Helper()
+	select {
+	case data := <-s.events:
+		m := serverMessage{}
+		require.NoError(s.t, json.Unmarshal(data, &m))
+		return m
+	case <-time.After(5 * time.Second):
+		require.FailNow(s.t, "no message received on the event stream")
+		return serverMessage{}
+	}
+}
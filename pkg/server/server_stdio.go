@@ -1,7 +1,19 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/channel"
 
 	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/handler"
@@ -9,13 +21,198 @@ import (
 
 type StdioServer struct {
 	*jrpc2.Server
+	ctx context.Context
 }
 
 func NewStdioServer(logger *slog.Logger, router Router) *StdioServer {
+	ctx := NewSessionContext(context.Background())
 	srv := jrpc2.NewServer(handler.Map(router), &jrpc2.ServerOptions{
-		Logger: SlogToLogBridge(logger),
+		Logger:    SlogToLogBridge(logger),
+		AllowPush: true, // for the notifications and requests sent to the client
+		NewContext: func() context.Context {
+			return ctx
+		},
 	})
 	return &StdioServer{
 		Server: srv,
+		ctx:    ctx,
+	}
+}
+
+// Start starts the server on the given channel, on which the requests of `Sample` and `Elicit` are also sent
+// (see `NewSessionChannel`)
+func (s *StdioServer) Start(ch channel.Channel) *jrpc2.Server {
+	return s.Server.Start(NewSessionChannel(s.ctx, ch))
+}
+
+// DefaultParentCheckInterval is the default interval at which `ServeStdio` checks if the parent process is still alive
+const DefaultParentCheckInterval = time.Second
+
+// StdioOptions configures `ServeStdio`
+type StdioOptions struct {
+	// Framing defaults to `channel.LineFraming`
+	Framing channel.Framing
+	// MaxMessageSize defaults to `channel.DefaultMaxMessageSize`
+	MaxMessageSize int
+	// Logger defaults to a text logger on the standard error
+	Logger *slog.Logger
+	// ParentCheckInterval defaults to `DefaultParentCheckInterval`. A negative value disables the check.
+	ParentCheckInterval time.Duration
+	// Stdin and Stdout default to the standard input and output of the process
+	Stdin  io.Reader
+	Stdout io.WriteCloser
+	// RedirectStdout replaces `os.Stdout` with `os.Stderr` while serving on the standard output, so that stray writes
+	// do not corrupt the stream of messages. This affects the whole process, and is not safe if other goroutines
+	// use `os.Stdout` concurrently.
+	RedirectStdout bool
+}
+
+// ServeStdio serves the given router on the standard input and output until the context is cancelled,
+// the standard input is closed, or the parent process exits, in which case it returns `nil`.
+// While serving, the default loggers are redirected to the standard error (and `os.Stdout` is replaced with `os.Stderr`
+// if `RedirectStdout` is set).
+// An error is returned if a message cannot be received, for example if it exceeds the maximum size.
+func ServeStdio(ctx context.Context, router Router, opts StdioOptions) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	stdin, stdout := opts.Stdin, opts.Stdout
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+		if opts.RedirectStdout {
+			os.Stdout = os.Stderr
+			defer func() {
+				os.Stdout = stdout.(*os.File)
+			}()
+		}
+	}
+	defaultLogger := slog.Default()
+	slog.SetDefault(logger)
+	defaultLogWriter := log.Writer()
+	log.SetOutput(os.Stderr)
+	defer func() {
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(defaultLogWriter)
+	}()
+
+	srv := NewStdioServer(logger, router)
+	// the responses to the requests sent to the client are received below the draining channel, which only counts
+	// the requests received from the client
+	srv.Server.Start(newDrainingChannel(NewSessionChannel(srv.ctx, channel.New(stdin, stdout, opts.Framing, opts.MaxMessageSize))))
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- srv.Wait()
+	}()
+
+	interval := opts.ParentCheckInterval
+	if interval == 0 {
+		interval = DefaultParentCheckInterval
+	}
+	var parentExited <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		parentExited = ticker.C
+	}
+	ppid := os.Getppid()
+	for {
+		select {
+		case err := <-stopped:
+			if err == nil || errors.Is(err, io.EOF) {
+				logger.Info("stdio server stopped: end of input")
+				return nil
+			}
+			return fmt.Errorf("stdio server stopped: %w", err)
+		case <-ctx.Done():
+			logger.Info("stdio server stopped: context cancelled")
+			srv.Stop()
+			return nil
+		case <-parentExited:
+			// the process is re-parented when its parent exits
+			if os.Getppid() != ppid {
+				logger.Info("stdio server stopped: parent process exited")
+				srv.Stop()
+				return nil
+			}
+		}
+	}
+}
+
+// drainingChannel delays the end of input until all the requests which were received so far have been answered,
+// since the server cancels the pending requests when its channel is closed
+type drainingChannel struct {
+	channel.Channel
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending int
+	closed  bool
+}
+
+func newDrainingChannel(ch channel.Channel) *drainingChannel {
+	c := &drainingChannel{
+		Channel: ch,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *drainingChannel) Recv() ([]byte, error) {
+	msg, err := c.Channel.Recv()
+	if errors.Is(err, io.EOF) {
+		c.mu.Lock()
+		for c.pending > 0 && !c.closed {
+			c.cond.Wait()
+		}
+		c.mu.Unlock()
+		return msg, err
+	}
+	if err != nil {
+		return msg, err
+	}
+	if reqs, perr := jrpc2.ParseRequests(msg); perr == nil {
+		c.mu.Lock()
+		for _, req := range reqs {
+			if req.ID != "" { // notifications are not answered
+				c.pending++
+			}
+		}
+		c.mu.Unlock()
 	}
+	return msg, nil
+}
+
+func (c *drainingChannel) Send(msg []byte) error {
+	err := c.Channel.Send(msg)
+	var msgs []struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if data := bytes.TrimSpace(msg); len(data) > 0 && data[0] != '[' {
+		msg = append(append([]byte{'['}, data...), ']')
+	}
+	if json.Unmarshal(msg, &msgs) != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range msgs {
+		// skip the requests sent to the client, and the errors which are not associated with a request
+		if m.Method == "" && len(m.ID) > 0 && string(m.ID) != "null" && c.pending > 0 {
+			c.pending--
+		}
+	}
+	c.cond.Broadcast()
+	return err
+}
+
+func (c *drainingChannel) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	return c.Channel.Close()
 }
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/channel"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestServeStdio(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+		Build()
+	listTools := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+
+	t.Run("line framing", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+
+		// when
+		err := server.ServeStdio(context.Background(), router, server.StdioOptions{
+			Logger: logger,
+			Stdin:  strings.NewReader(listTools + "\n"),
+			Stdout: nopWriteCloser{out},
+		})
+
+		// then
+		require.NoError(t, err) // end of input
+		resp := api.JSONRPCResponse{}
+		require.NoError(t, json.Unmarshal(bytes.TrimSuffix(out.Bytes(), []byte("\n")), &resp))
+		assert.Equal(t, api.IntRequestId(1), resp.Id)
+	})
+
+	t.Run("header framing", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+
+		// when
+		err := server.ServeStdio(context.Background(), router, server.StdioOptions{
+			Framing: channel.HeaderFraming,
+			Logger:  logger,
+			Stdin:   strings.NewReader("Content-Length: 46\r\n\r\n" + listTools),
+			Stdout:  nopWriteCloser{out},
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(out.String(), "Content-Length: "))
+	})
+
+	t.Run("message too large", func(t *testing.T) {
+		// when
+		err := server.ServeStdio(context.Background(), router, server.StdioOptions{
+			MaxMessageSize: 16,
+			Logger:         logger,
+			Stdin:          strings.NewReader(listTools + "\n"),
+			Stdout:         nopWriteCloser{io.Discard},
+		})
+
+		// then
+		var tooLarge *channel.MessageTooLargeError
+		require.ErrorAs(t, err, &tooLarge)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		// given
+		stdin, _ := io.Pipe() // never closed
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		// when
+		err := server.ServeStdio(ctx, router, server.StdioOptions{
+			Logger: logger,
+			Stdin:  stdin,
+			Stdout: nopWriteCloser{io.Discard},
+		})
+
+		// then
+		require.NoError(t, err)
+	})
+}
@@ -1,13 +1,18 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -25,13 +30,13 @@ type StreamableHTTPServer struct {
 
 // Start starts an HTTP server in a separate go routine and returns a Server interface that can be used to stop the server.
 // Use `srv.Wait()` to wait for the server to receive a shutdown signal (`syscall.SIGINT` or `syscall.SIGTERM`)
-func NewStreamableHTTPServer(logger *slog.Logger, router Router, port int) *StreamableHTTPServer {
+func NewStreamableHTTPServer(logger *slog.Logger, router Router, port int, opts ...HTTPOption) *StreamableHTTPServer {
 	mux := http.NewServeMux()
 	mux.Handle("/_health", LoggingMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger.Debug("Health check request", "method", r.Method, "uri", r.RequestURI)
 		w.WriteHeader(http.StatusOK)
 	})))
-	mux.Handle("/mcp", NewHTTPHandler(router, logger))
+	mux.Handle("/mcp", NewHTTPHandler(router, logger, opts...))
 	srv := &http.Server{
 		Addr:        fmt.Sprintf(":%d", port),
 		Handler:     mux,
@@ -81,14 +86,365 @@ func (s *StreamableHTTPServer) Addr() string {
 	return s.srv.Addr
 }
 
-func NewHTTPHandler(router Router, logger *slog.Logger) http.Handler {
-	return jhttp.NewBridge(handler.Map(router), &jhttp.BridgeOptions{
-		Client: &jrpc2.ClientOptions{
-			Logger: SlogToLogBridge(logger),
-		},
-		Server: &jrpc2.ServerOptions{
-			Logger: SlogToLogBridge(logger),
-			RPCLog: SlogToRPCLogBridge(logger),
-		},
-	})
+const (
+	// DefaultMaxSessions is the default maximum number of concurrent HTTP sessions
+	DefaultMaxSessions = 1000
+	// DefaultSessionIdleTimeout is the default duration after which an inactive HTTP session is closed
+	DefaultSessionIdleTimeout = 30 * time.Minute
+)
+
+// HTTPOption configures the handler returned by `NewHTTPHandler`
+type HTTPOption func(*httpHandler)
+
+// WithMaxSessions sets the maximum number of concurrent sessions.
+// Once the limit is reached, `initialize` requests are rejected with a `503 Service Unavailable` status.
+func WithMaxSessions(maxSessions int) HTTPOption {
+	return func(h *httpHandler) {
+		h.maxSessions = maxSessions
+	}
+}
+
+// WithSessionIdleTimeout sets the duration after which a session which received no request is closed
+func WithSessionIdleTimeout(timeout time.Duration) HTTPOption {
+	return func(h *httpHandler) {
+		h.idleTimeout = timeout
+	}
+}
+
+// WithAllowedOrigins rejects the requests sent from other origins (see `OriginValidationMiddleware`)
+func WithAllowedOrigins(origins ...string) HTTPOption {
+	return func(h *httpHandler) {
+		h.allowedOrigins = origins
+	}
+}
+
+// WithCORS enables CORS for browser-based clients (see `CORSMiddleware`).
+// If no allowed origins are set in the options, the origins set with `WithAllowedOrigins` are used.
+func WithCORS(opts CORSOptions) HTTPOption {
+	return func(h *httpHandler) {
+		h.cors = &opts
+	}
+}
+
+// NewHTTPHandler returns the handler for the MCP endpoint.
+// A new session is created for each `initialize` request, and its ID is returned in the `Mcp-Session-Id` response header.
+// Requests without a session ID are handled in a shared, anonymous session.
+// The client of a session receives the requests and notifications of the server on the event stream opened with a
+// `GET` request, and sends its responses to these requests with `POST` requests, which are answered with a
+// `202 Accepted` status.
+func NewHTTPHandler(router Router, logger *slog.Logger, opts ...HTTPOption) http.Handler {
+	h := &httpHandler{
+		router:         router,
+		logger:         logger,
+		now:            time.Now,
+		maxSessions:    DefaultMaxSessions,
+		idleTimeout:    DefaultSessionIdleTimeout,
+		maxBodySize:    DefaultMaxBodySize,
+		maxJSONDepth:   DefaultMaxJSONDepth,
+		maxBatchLength: DefaultMaxBatchLength,
+		sessions:       map[string]*httpSession{},
+		anonymous:      newHTTPSession(router, logger, ""),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	var next http.Handler = h
+	if len(h.allowedOrigins) > 0 {
+		next = OriginValidationMiddleware(h.allowedOrigins, next)
+	}
+	if h.cors != nil {
+		cors := *h.cors
+		if len(cors.AllowedOrigins) == 0 {
+			cors.AllowedOrigins = h.allowedOrigins
+		}
+		next = CORSMiddleware(cors, next)
+	}
+	return next
+}
+
+type httpHandler struct {
+	router         Router
+	logger         *slog.Logger
+	now            func() time.Time
+	maxSessions    int
+	idleTimeout    time.Duration
+	maxBodySize    int64
+	maxJSONDepth   int
+	maxBatchLength int
+	allowedOrigins []string
+	cors           *CORSOptions
+	mu             sync.Mutex
+	sessions       map[string]*httpSession
+	anonymous      *httpSession
+}
+
+type httpSession struct {
+	bridge   jhttp.Bridge
+	requests *clientRequests
+	events   *eventStream
+	lastSeen time.Time
+}
+
+// newHTTPSession returns a session whose requests and notifications are sent to the client on the event stream
+// opened with a `GET` request (they are dropped while no stream is open, and `Sample` and `Elicit` return an error)
+func newHTTPSession(router Router, logger *slog.Logger, sessionID string) *httpSession {
+	ctx := NewSessionContext(ContextWithSessionID(context.Background(), sessionID))
+	events := &eventStream{}
+	requests := sessionFromContext(ctx).requests
+	requests.attach(events.send)
+	onNotify := func(req *jrpc2.Request) {
+		m := clientMessage{Method: req.Method()}
+		if req.HasParams() {
+			m.Params = json.RawMessage(req.ParamString())
+		}
+		if err := requests.sendMessage(m); err != nil {
+			logger.Debug("failed to notify the client", "session", sessionID, "method", req.Method(), "error", err)
+		}
+	}
+	return &httpSession{
+		bridge: jhttp.NewBridge(handler.Map(router), &jhttp.BridgeOptions{
+			Client: &jrpc2.ClientOptions{
+				Logger:   SlogToLogBridge(logger),
+				OnNotify: onNotify,
+			},
+			Server: &jrpc2.ServerOptions{
+				Logger:    SlogToLogBridge(logger),
+				RPCLog:    SlogToRPCLogBridge(logger),
+				AllowPush: true, // for the notifications forwarded to the event stream by the client of the bridge
+				NewContext: func() context.Context {
+					return ctx
+				},
+			},
+		}),
+		requests: requests,
+		events:   events,
+	}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(SessionIDHeader)
+	switch r.Method {
+	case http.MethodDelete:
+		h.closeSession(w, sessionID)
+		return
+	case http.MethodGet:
+		h.openEventStream(w, r, sessionID)
+		return
+	}
+	var body []byte
+	var reqs []*jrpc2.ParsedRequest
+	if r.Method == http.MethodPost {
+		var ok bool
+		body, reqs, ok = h.readBody(w, r)
+		if !ok {
+			return
+		}
+	}
+	var s *httpSession
+	switch {
+	case sessionID != "":
+		var found bool
+		if s, found = h.lookupSession(sessionID); !found {
+			http.Error(w, fmt.Sprintf("session '%s' does not exist", sessionID), http.StatusNotFound)
+			return
+		}
+	case isInitializeRequest(reqs):
+		var err error
+		sessionID, s, err = h.newSession()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(SessionIDHeader, sessionID)
+	default:
+		s = h.anonymous
+	}
+	if body != nil {
+		// the responses to the requests sent to the client are accepted without being passed to the server
+		if body = s.requests.deliver(body); body == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	rw := &rateLimitedResponseWriter{ResponseWriter: w}
+	s.bridge.ServeHTTP(rw, r)
+	rw.flush()
+}
+
+// openEventStream serves the event stream on which the requests and notifications of the server are sent to the
+// client of the session, until the client disconnects or the session is closed
+func (h *httpHandler) openEventStream(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if sessionID == "" {
+		http.Error(w, "event streams are only supported in sessions", http.StatusMethodNotAllowed)
+		return
+	}
+	if !acceptsEventStream(r) {
+		http.Error(w, "the client must accept 'text/event-stream' responses", http.StatusNotAcceptable)
+		return
+	}
+	s, found := h.lookupSession(sessionID)
+	if !found {
+		http.Error(w, fmt.Sprintf("session '%s' does not exist", sessionID), http.StatusNotFound)
+		return
+	}
+	h.logger.Debug("event stream opened", "session", sessionID)
+	s.events.serve(w, r)
+	h.logger.Debug("event stream closed", "session", sessionID)
+}
+
+func (h *httpHandler) newSession() (string, *httpSession, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expireSessionsLocked()
+	if len(h.sessions) >= h.maxSessions {
+		h.logger.Warn("session rejected", "sessions", len(h.sessions))
+		return "", nil, fmt.Errorf("too many sessions")
+	}
+	sessionID := newSessionID()
+	s := newHTTPSession(h.router, h.logger, sessionID)
+	s.lastSeen = h.now()
+	h.sessions[sessionID] = s
+	h.logger.Debug("session created", "session", sessionID)
+	return sessionID, s, nil
+}
+
+func (h *httpHandler) lookupSession(sessionID string) (*httpSession, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expireSessionsLocked()
+	s, found := h.sessions[sessionID]
+	if !found {
+		return nil, false
+	}
+	s.lastSeen = h.now()
+	return s, true
+}
+
+// expireSessionsLocked closes the sessions which have been idle for longer than the configured timeout.
+// The sessions in which the client keeps an event stream open are not idle.
+func (h *httpHandler) expireSessionsLocked() {
+	now := h.now()
+	for id, s := range h.sessions {
+		if s.events.open() {
+			s.lastSeen = now
+			continue
+		}
+		if now.Sub(s.lastSeen) > h.idleTimeout {
+			delete(h.sessions, id)
+			h.logger.Debug("session expired", "session", id)
+			go h.closeBridge(id, s) // closing waits for the in-flight requests to complete
+		}
+	}
+}
+
+func (h *httpHandler) closeSession(w http.ResponseWriter, sessionID string) {
+	h.mu.Lock()
+	s, found := h.sessions[sessionID]
+	delete(h.sessions, sessionID)
+	h.mu.Unlock()
+	if !found {
+		http.Error(w, fmt.Sprintf("session '%s' does not exist", sessionID), http.StatusNotFound)
+		return
+	}
+	h.closeBridge(sessionID, s)
+	h.logger.Debug("session closed", "session", sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *httpHandler) closeBridge(sessionID string, s *httpSession) {
+	s.events.close()
+	if err := s.bridge.Close(); err != nil {
+		h.logger.Warn("error while closing session", "session", sessionID, "error", err)
+	}
+}
+
+func isInitializeRequest(reqs []*jrpc2.ParsedRequest) bool {
+	for _, req := range reqs {
+		if req.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitedResponseWriter replaces the `200 OK` status with `429 Too Many Requests` when the response
+// (or every response in a batch) is a `RateLimitedCode` error. A batch in which only some calls were rejected keeps
+// the `200 OK` status, so that the client does not retry the calls which succeeded. The body is only decoded if it contains the error code.
+type rateLimitedResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *rateLimitedResponseWriter) WriteHeader(status int) {
+	// defer until the body is written
+	w.status = status
+}
+
+func (w *rateLimitedResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		status := w.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status == http.StatusOK {
+			if retryAfter, limited := rateLimited(b); limited {
+				if retryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				}
+				status = http.StatusTooManyRequests
+			}
+		}
+		w.ResponseWriter.WriteHeader(status)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// flush writes the status if the response has no body (eg: `204 No Content`)
+func (w *rateLimitedResponseWriter) flush() {
+	if !w.wroteHeader && w.status != 0 {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+var rateLimitedCode = []byte(strconv.Itoa(int(RateLimitedCode)))
+
+type rateLimitedResponse struct {
+	Error *struct {
+		Code jrpc2.Code `json:"code"`
+		Data struct {
+			RetryAfter int `json:"retryAfter"`
+		} `json:"data"`
+	} `json:"error"`
+}
+
+// rateLimited checks if the given body is a JSON-RPC response (or a batch of responses) which is a `RateLimitedCode` error
+// (or only contains such errors), and returns the largest number of seconds after which the requests can be retried
+// (or 0 if unknown)
+func rateLimited(body []byte) (int, bool) {
+	if !bytes.Contains(body, rateLimitedCode) {
+		return 0, false
+	}
+	var resps []rateLimitedResponse
+	if err := json.Unmarshal(body, &resps); err != nil {
+		var resp rateLimitedResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return 0, false
+		}
+		resps = []rateLimitedResponse{resp}
+	}
+	if len(resps) == 0 {
+		return 0, false
+	}
+	retryAfter := 0
+	for _, resp := range resps {
+		if resp.Error == nil || resp.Error.Code != RateLimitedCode {
+			return 0, false
+		}
+		retryAfter = max(retryAfter, resp.Error.Data.RetryAfter)
+	}
+	return retryAfter, true
 }
@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPEventStream(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("my-tool"), EmptyToolHandle).
+		Build()
+	session := newHTTPTestSession(t, router, `{}`)
+
+	testCases := []struct {
+		name           string
+		sessionID      string
+		accept         string
+		expectedStatus int
+	}{
+		{
+			name:           "without session",
+			accept:         "text/event-stream",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "unknown session",
+			sessionID:      "unknown",
+			accept:         "text/event-stream",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "event stream not accepted",
+			sessionID:      session.id,
+			accept:         "application/json",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, session.url, nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", testCase.accept)
+			if testCase.sessionID != "" {
+				req.Header.Set(server.SessionIDHeader, testCase.sessionID)
+			}
+
+			// when
+			resp, err := http.DefaultClient.Do(req)
+
+			// then
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, testCase.expectedStatus, resp.StatusCode)
+		})
+	}
+
+	t.Run("closed with the session", func(t *testing.T) {
+		// given
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, session.url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set(server.SessionIDHeader, session.id)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		closed := make(chan error, 1)
+		go func() {
+			_, readErr := io.ReadAll(resp.Body)
+			closed <- readErr
+		}()
+
+		// when
+		del, err := http.NewRequestWithContext(context.Background(), http.MethodDelete, session.url, nil)
+		require.NoError(t, err)
+		del.Header.Set(server.SessionIDHeader, session.id)
+		delResp, err := http.DefaultClient.Do(del)
+
+		// then
+		require.NoError(t, err)
+		defer delResp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, delResp.StatusCode)
+		select {
+		case err := <-closed:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the event stream was not closed")
+		}
+	})
+}
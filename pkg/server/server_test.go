@@ -30,6 +30,20 @@ var EmptyToolHandle server.ToolHandleFunc = func(_ context.Context, _ api.CallTo
 	return api.CallToolResult{}, nil
 }
 
+// TextResourceHandle returns a handle which reads a text resource with the given content
+func TextResourceHandle(text string) server.ResourceHandleFunc {
+	return func(_ context.Context, params api.ReadResourceRequestParams) (api.ReadResourceResult, error) {
+		return api.ReadResourceResult{
+			Contents: []api.ReadResourceResultContentsElem{
+				{
+					Uri:  params.Uri,
+					Text: text,
+				},
+			},
+		}, nil
+	}
+}
+
 func TestServer(t *testing.T) {
 
 	// given
@@ -37,8 +51,8 @@ func TestServer(t *testing.T) {
 	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
 		WithPrompt(api.NewPrompt("my-first-prompt"), EmptyPromptHandle).
 		WithPrompt(api.NewPrompt("my-second-prompt"), EmptyPromptHandle).
-		WithResource(api.NewResource("my-first-resource", "https://example.com/my-first-resource"), EmptyResourceHandle).
-		WithResource(api.NewResource("my-second-resource", "https://example.com/my-second-resource"), EmptyResourceHandle).
+		WithResource(api.NewResource("my-first-resource", "https://example.com/my-first-resource"), TextResourceHandle("first")).
+		WithResource(api.NewResource("my-second-resource", "https://example.com/my-second-resource"), TextResourceHandle("second")).
 		WithTool(api.NewTool("my-first-tool"), EmptyToolHandle).
 		WithTool(api.NewTool("my-second-tool"), EmptyToolHandle).
 		Build()
@@ -135,6 +149,29 @@ func TestServer(t *testing.T) {
 				assert.JSONEq(t, string(expectedJSON), resp.ResultString())
 			})
 
+			t.Run("read resource", func(t *testing.T) {
+				// when
+				resp, err := cl.Call(context.Background(), "resources/read", api.ReadResourceRequestParams{
+					Uri: "https://example.com/my-second-resource",
+				})
+
+				// then
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"contents":[{"uri":"https://example.com/my-second-resource","text":"second"}]}`, resp.ResultString())
+			})
+
+			t.Run("read unknown resource", func(t *testing.T) {
+				// when
+				_, err := cl.Call(context.Background(), "resources/read", api.ReadResourceRequestParams{
+					Uri: "my-second-resource", // name instead of URI
+				})
+
+				// then
+				var rpcErr *jrpc2.Error
+				require.ErrorAs(t, err, &rpcErr)
+				assert.Equal(t, server.ResourceNotFoundCode, rpcErr.Code)
+			})
+
 			t.Run("list tools", func(t *testing.T) {
 				// when
 				resp, err := cl.Call(context.Background(), "tools/list", api.ListResourcesRequestParams{})
@@ -149,9 +186,6 @@ func TestServer(t *testing.T) {
 							InputSchema: api.ToolInputSchema{
 								Type: "object",
 							},
-							OutputSchema: &api.ToolOutputSchema{
-								Type: "object",
-							},
 						},
 						{
 							Name:        "my-second-tool",
@@ -159,9 +193,6 @@ func TestServer(t *testing.T) {
 							InputSchema: api.ToolInputSchema{
 								Type: "object",
 							},
-							OutputSchema: &api.ToolOutputSchema{
-								Type: "object",
-							},
 						},
 					},
 				}
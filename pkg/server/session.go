@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// SessionIDHeader is the HTTP header used by the Streamable HTTP transport to carry the session ID
+const SessionIDHeader = "Mcp-Session-Id"
+
+type sessionIDKey struct{}
+
+// ContextWithSessionID returns a copy of the given context which carries the given session ID
+func ContextWithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionIDFromContext returns the ID of the session in which the current request is handled,
+// or an empty string if the transport does not support sessions (eg: stdio)
+func SessionIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(sessionIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // never returns an error
+	return hex.EncodeToString(b)
+}
+
+// session holds the state negotiated with the client during the initialization
+type session struct {
+	mu                 sync.RWMutex
+	protocolVersion    string
+	clientCapabilities *api.ClientCapabilities
+	requests           *clientRequests
+}
+
+type sessionKey struct{}
+
+// NewSessionContext returns a copy of the given context which holds the state of a new session, such as the
+// protocol version negotiated during the initialization. It must be the base context of all the requests handled
+// by a `jrpc2.Server` (see `jrpc2.ServerOptions.NewContext`), otherwise the client is assumed to use the latest
+// protocol version. The server should also be started on a channel returned by `NewSessionChannel`.
+func NewSessionContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{
+		requests: newClientRequests(),
+	})
+}
+
+func sessionFromContext(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+// ProtocolVersionFromContext returns the protocol version negotiated with the client,
+// or the latest version if the session was not initialized
+func ProtocolVersionFromContext(ctx context.Context) string {
+	if s := sessionFromContext(ctx); s != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.protocolVersion != "" {
+			return s.protocolVersion
+		}
+	}
+	return api.LatestProtocolVersion
+}
+
+func (s *session) setProtocolVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+}
+
+// ClientCapabilitiesFromContext returns the capabilities declared by the client during the initialization,
+// and `false` if the session was not initialized
+func ClientCapabilitiesFromContext(ctx context.Context) (api.ClientCapabilities, bool) {
+	if s := sessionFromContext(ctx); s != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.clientCapabilities != nil {
+			return *s.clientCapabilities, true
+		}
+	}
+	return api.ClientCapabilities{}, false
+}
+
+func (s *session) setClientCapabilities(capabilities api.ClientCapabilities) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientCapabilities = &capabilities
+}
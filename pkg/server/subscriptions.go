@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// Subscriptions keeps track of the initialized sessions and of the resources they subscribed to, so that
+// notifications can be sent outside of the requests (eg: when a resource is updated).
+// The sessions whose transport does not support server-initiated messages are forgotten on the first notification.
+type Subscriptions struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	// the servers of the initialized sessions, with the URIs of the resources they subscribed to
+	sessions map[*jrpc2.Server]map[string]bool
+	// funcs called on each resource update, regardless of the subscriptions
+	listeners []func(uri string)
+}
+
+// NewSubscriptions returns a new, empty set of subscriptions
+func NewSubscriptions(logger *slog.Logger) *Subscriptions {
+	return &Subscriptions{
+		logger:   logger,
+		sessions: map[*jrpc2.Server]map[string]bool{},
+	}
+}
+
+// WithSubscriptions enables the `resources/subscribe` and `resources/unsubscribe` requests, whose subscriptions
+// are kept in the given value
+func (b *RouterBuilder) WithSubscriptions(s *Subscriptions) *RouterBuilder {
+	b.subscriptions = s
+	b.capabilities.Resources.Subscribe = api.BoolPtr(true)
+	return b
+}
+
+// withSessionTracking registers the sessions once they are initialized
+func (s *Subscriptions) withSessionTracking(initialize jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		result, err := initialize(ctx, req)
+		if err != nil {
+			return result, err
+		}
+		if srv := jrpc2.ServerFromContext(ctx); srv != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if _, found := s.sessions[srv]; !found {
+				s.sessions[srv] = map[string]bool{}
+			}
+		}
+		return result, nil
+	}
+}
+
+func (s *Subscriptions) subscribe(router *resourceRouter) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		params := api.SubscribeRequestParams{}
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, invalidParamsError(req, err)
+		}
+		s.logger.Debug("subscribe to resource", "uri", params.Uri)
+		if _, ok := router.lookup(params.Uri); !ok {
+			return nil, ResourceNotFoundError(params.Uri)
+		}
+		srv := jrpc2.ServerFromContext(ctx)
+		if srv == nil {
+			return nil, jrpc2.Errorf(jrpc2.InternalError, "no server in context")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.sessions[srv] == nil {
+			s.sessions[srv] = map[string]bool{}
+		}
+		s.sessions[srv][params.Uri] = true
+		return &api.Result{}, nil
+	}
+}
+
+func (s *Subscriptions) unsubscribe(ctx context.Context, req *jrpc2.Request) (any, error) {
+	params := api.UnsubscribeRequestParams{}
+	if err := req.UnmarshalParams(&params); err != nil {
+		return nil, invalidParamsError(req, err)
+	}
+	s.logger.Debug("unsubscribe from resource", "uri", params.Uri)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if subscriptions, found := s.sessions[jrpc2.ServerFromContext(ctx)]; found {
+		delete(subscriptions, params.Uri)
+	}
+	return &api.Result{}, nil
+}
+
+// Notify sends the given notification to all the sessions (eg: `notifications/resources/list_changed`)
+func (s *Subscriptions) Notify(method string, params any) {
+	s.mu.Lock()
+	servers := slices.Collect(maps.Keys(s.sessions))
+	s.mu.Unlock()
+	for _, srv := range servers {
+		s.send(srv, method, params)
+	}
+}
+
+// OnResourceUpdated registers a func which is called with the URI of each updated resource
+// (eg: `ResourceCache.Invalidate`)
+func (s *Subscriptions) OnResourceUpdated(f func(uri string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, f)
+}
+
+// NotifyResourceUpdated calls the funcs registered with `OnResourceUpdated`, then sends a
+// `notifications/resources/updated` notification to the sessions which subscribed to the resource with the given URI
+func (s *Subscriptions) NotifyResourceUpdated(uri string) {
+	s.mu.Lock()
+	listeners := slices.Clone(s.listeners)
+	servers := []*jrpc2.Server{}
+	for srv, subscriptions := range s.sessions {
+		if subscriptions[uri] {
+			servers = append(servers, srv)
+		}
+	}
+	s.mu.Unlock()
+	for _, f := range listeners {
+		f(uri)
+	}
+	for _, srv := range servers {
+		s.send(srv, "notifications/resources/updated", api.ResourceUpdatedNotificationParams{
+			Uri: uri,
+		})
+	}
+}
+
+// send sends the notification to the given server, and forgets the session if it cannot receive it
+// (eg: because the server stopped, or because its transport does not support server-initiated messages)
+func (s *Subscriptions) send(srv *jrpc2.Server, method string, params any) {
+	if err := srv.Notify(context.Background(), method, params); err != nil {
+		s.logger.Debug("failed to notify the client", "method", method, "error", err)
+		s.mu.Lock()
+		delete(s.sessions, srv)
+		s.mu.Unlock()
+	}
+}
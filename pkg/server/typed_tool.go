@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/creachadair/jrpc2"
+	api "github.com/xcoulon/converse-mcp/pkg/api"
+)
+
+// TypedToolHandleFunc handles the calls to a tool with the arguments decoded into an `In` value
+type TypedToolHandleFunc[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// AddTypedTool registers a tool whose arguments are decoded into an `In` value before calling the given func.
+// The input and output schemas of the tool are derived from the `In` and `Out` types (see `api.SchemaFor`).
+// Arguments which cannot be decoded are rejected with an `invalid params` error.
+// The `Out` value returned by the func is set as the structured content of the result (wrapped in a `result` property
+// if it is not encoded as a JSON object), along with its serialized JSON in a text content block.
+// Errors returned by the func are reported in the result, with the `isError` flag set, so that the LLM can see them,
+// except for `*jrpc2.Error` values which are returned as protocol errors.
+func AddTypedTool[In, Out any](b *RouterBuilder, tool api.Tool, handle TypedToolHandleFunc[In, Out], opts ...ToolOption) *RouterBuilder {
+	tool = tool.WithInputSchemaFor(*new(In)).WithOutputSchemaFor(*new(Out))
+	return b.WithTool(tool, typedToolHandle(tool.Name, handle), opts...)
+}
+
+func typedToolHandle[In, Out any](name string, handle TypedToolHandleFunc[In, Out]) ToolHandleFunc {
+	return func(ctx context.Context, params api.CallToolRequestParams) (api.CallToolResult, error) {
+		var in In
+		if err := decodeArguments(params.Arguments, &in); err != nil {
+			return api.CallToolResult{}, jrpc2.Errorf(jrpc2.InvalidParams, "invalid arguments for tool '%s': %v", name, err)
+		}
+		out, err := handle(ctx, in)
+		if err != nil {
+			return ToolResultFromError(err)
+		}
+		return newStructuredResult(out)
+	}
+}
+
+func decodeArguments(args map[string]any, in any) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, in); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("argument '%s' must be of type %s, not %s", typeErr.Field, typeErr.Type.Kind(), typeErr.Value)
+		}
+		return err
+	}
+	return nil
+}
+
+func newStructuredResult(out any) (api.CallToolResult, error) {
+	data, err := json.Marshal(out)
+	if err != nil {
+		return api.CallToolResult{}, fmt.Errorf("error while marshalling the tool result: %w", err)
+	}
+	structured := map[string]any{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(data, &structured); err != nil {
+			return api.CallToolResult{}, fmt.Errorf("error while marshalling the tool result: %w", err)
+		}
+	} else {
+		structured["result"] = json.RawMessage(data)
+		if data, err = json.Marshal(structured); err != nil {
+			return api.CallToolResult{}, fmt.Errorf("error while marshalling the tool result: %w", err)
+		}
+	}
+	return api.CallToolResult{
+		Content: []api.CallToolResultContentElem{
+			api.Text(string(data)),
+		},
+		StructuredContent: structured,
+	}, nil
+}
@@ -0,0 +1,130 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sumInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type sumOutput struct {
+	Sum int `json:"sum"`
+}
+
+func TestAddTypedTool(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	b := server.NewRouterBuilder("converse-mcp", "0.1", logger)
+	server.AddTypedTool(b, api.NewTool("sum"), func(_ context.Context, in sumInput) (sumOutput, error) {
+		if in.A < 0 || in.B < 0 {
+			return sumOutput{}, errors.New("negative numbers are not supported")
+		}
+		return sumOutput{Sum: in.A + in.B}, nil
+	})
+	server.AddTypedTool(b, api.NewTool("unvalidated-sum"), func(_ context.Context, in sumInput) (sumOutput, error) {
+		return sumOutput{Sum: in.A + in.B}, nil
+	}, server.WithoutInputValidation())
+	server.AddTypedTool(b, api.NewTool("fail"), func(_ context.Context, _ struct{}) (struct{}, error) {
+		return struct{}{}, jrpc2.Errorf(jrpc2.InternalError, "mock error")
+	})
+	cl := mcptest.NewClient(t, b.Build())
+
+	t.Run("schemas", func(t *testing.T) {
+		// when
+		result, err := cl.ListTools(context.Background())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result.Tools, 3)
+		assert.Equal(t, []string{"a", "b"}, result.Tools[0].InputSchema.Required)
+		assert.Equal(t, map[string]any{"type": "integer"}, result.Tools[0].OutputSchema.Properties["sum"])
+	})
+
+	t.Run("structured result", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "sum", map[string]any{"a": 1, "b": 2})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolResult(t, api.CallToolResult{
+			Content: []api.CallToolResultContentElem{
+				api.Text(`{"sum":3}`),
+			},
+			StructuredContent: map[string]any{"sum": 3},
+		}, result)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "sum", map[string]any{"a": "one", "b": 2})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolError(t, result)
+		mcptest.AssertTextContent(t, result, "invalid arguments for tool 'sum': 'a' must be of type integer, not string")
+	})
+
+	t.Run("undecodable arguments", func(t *testing.T) {
+		// when
+		_, err := cl.CallTool(context.Background(), "unvalidated-sum", map[string]any{"a": "one", "b": 2})
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, jrpc2.InvalidParams, rpcErr.Code)
+		assert.Equal(t, "invalid arguments for tool 'unvalidated-sum': argument 'a' must be of type int, not string", rpcErr.Message)
+	})
+
+	t.Run("error result", func(t *testing.T) {
+		// when
+		result, err := cl.CallTool(context.Background(), "sum", map[string]any{"a": -1, "b": 2})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertToolError(t, result)
+		mcptest.AssertTextContent(t, result, "negative numbers are not supported")
+	})
+
+	t.Run("protocol error", func(t *testing.T) {
+		// when
+		_, err := cl.CallTool(context.Background(), "fail", nil)
+
+		// then
+		var rpcErr *jrpc2.Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, jrpc2.InternalError, rpcErr.Code)
+	})
+
+	t.Run("non-object output", func(t *testing.T) {
+		// given
+		b := server.NewRouterBuilder("converse-mcp", "0.1", logger)
+		server.AddTypedTool(b, api.NewTool("count"), func(_ context.Context, in struct {
+			Items []string `json:"items"`
+		}) (int, error) {
+			return len(in.Items), nil
+		})
+		cl := mcptest.NewClient(t, b.Build())
+
+		// when
+		result, err := cl.CallTool(context.Background(), "count", map[string]any{"items": []string{"a", "b"}})
+
+		// then
+		require.NoError(t, err)
+		mcptest.AssertStructuredContent(t, map[string]any{"result": 2}, result)
+		mcptest.AssertTextContent(t, result, `{"result":2}`)
+	})
+}
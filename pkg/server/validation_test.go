@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputValidation(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	tool := api.NewTool("search").
+		WithInputProperty("query", api.TypeString, "the query", true).
+		WithInputProperty("limit", api.TypeInteger, "the maximum number of results", false)
+	tool.InputSchema.Properties["limit"]["minimum"] = 1
+	tool.InputSchema.Properties["sort"] = map[string]any{"type": "string", "enum": []string{"asc", "desc"}}
+	unvalidatedTool := tool
+	unvalidatedTool.Name = "unvalidated-search"
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(tool, EmptyToolHandle).
+		WithTool(unvalidatedTool, EmptyToolHandle, server.WithoutInputValidation()).
+		Build()
+	cl := mcptest.NewClient(t, router)
+
+	testCases := []struct {
+		name          string
+		tool          string
+		args          map[string]any
+		expectedError string
+	}{
+		{
+			name: "valid arguments",
+			tool: "search",
+			args: map[string]any{"query": "foo", "limit": 10, "sort": "asc"},
+		},
+		{
+			name:          "missing required argument",
+			tool:          "search",
+			args:          nil,
+			expectedError: "invalid arguments for tool 'search': 'query' is required",
+		},
+		{
+			name:          "invalid arguments",
+			tool:          "search",
+			args:          map[string]any{"query": 1, "limit": 0, "sort": "random"},
+			expectedError: `invalid arguments for tool 'search': 'limit' must be greater than or equal to 1; 'query' must be of type string, not integer; 'sort' must be one of "asc", "desc"`,
+		},
+		{
+			name: "validation disabled",
+			tool: "unvalidated-search",
+			args: map[string]any{"query": 1},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// when
+			result, err := cl.CallTool(context.Background(), testCase.tool, testCase.args)
+
+			// then
+			require.NoError(t, err)
+			if testCase.expectedError == "" {
+				require.Nil(t, result.IsError)
+				return
+			}
+			mcptest.AssertToolError(t, result)
+			mcptest.AssertTextContent(t, result, testCase.expectedError)
+		})
+	}
+}
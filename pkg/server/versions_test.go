@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/xcoulon/converse-mcp/pkg/api"
+	"github.com/xcoulon/converse-mcp/pkg/mcptest"
+	"github.com/xcoulon/converse-mcp/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolVersions(t *testing.T) {
+
+	// given
+	logger := slog.New(slog.DiscardHandler)
+	router := server.NewRouterBuilder("converse-mcp", "0.1", logger).
+		WithTool(api.NewTool("count").
+			WithTitle("Count").
+			WithOutputProperty("count", api.TypeInteger, "the count", true),
+			func(_ context.Context, _ api.CallToolRequestParams) (api.CallToolResult, error) {
+				return api.CallToolResult{
+					Content:           []api.CallToolResultContentElem{},
+					StructuredContent: map[string]any{"count": 1},
+				}, nil
+			}).
+		Build()
+
+	t.Run("latest", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router)
+
+		// when
+		tools, err := cl.ListTools(context.Background())
+		require.NoError(t, err)
+		result, err := cl.CallTool(context.Background(), "count", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, api.LatestProtocolVersion, cl.InitializeResult.ProtocolVersion)
+		assert.NotNil(t, tools.Tools[0].Title)
+		assert.NotNil(t, tools.Tools[0].OutputSchema)
+		mcptest.AssertStructuredContent(t, map[string]any{"count": 1}, result)
+	})
+
+	t.Run("2024-11-05", func(t *testing.T) {
+		// given
+		cl := mcptest.NewClient(t, router, mcptest.WithProtocolVersion(api.ProtocolVersion20241105))
+
+		// when
+		tools, err := cl.ListTools(context.Background())
+		require.NoError(t, err)
+		result, err := cl.CallTool(context.Background(), "count", nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, api.ProtocolVersion20241105, cl.InitializeResult.ProtocolVersion)
+		assert.Nil(t, tools.Tools[0].Title)
+		assert.Nil(t, tools.Tools[0].OutputSchema)
+		assert.Nil(t, tools.Tools[0].Annotations)
+		assert.Nil(t, result.StructuredContent)
+		mcptest.AssertTextContent(t, result, `{"count":1}`)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		// when
+		cl := mcptest.NewClient(t, router, mcptest.WithProtocolVersion("2099-01-01"))
+
+		// then
+		assert.Equal(t, api.LatestProtocolVersion, cl.InitializeResult.ProtocolVersion)
+	})
+}